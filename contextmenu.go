@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// remindMessageCommand is the message context-menu command that sets a
+// reminder about the selected message.
+const remindMessageCommand = "Remind me about this message"
+
+const (
+	// maxQuoteLength caps the excerpt of the source message kept on a
+	// reminder, leaving room for the rest of the delivery.
+	maxQuoteLength = 300
+	// pendingMessageTTL is how long the selected message is remembered
+	// while its modal is open.
+	pendingMessageTTL = 15 * time.Minute
+)
+
+var messageLinkRe = regexp.MustCompile(`^https://(?:\w+\.)?discord(?:app)?\.com/channels/(\d+|@me)/(\d+)/(\d+)$`)
+
+// pendingMessage is the message a context-menu modal was opened for. The
+// modal submission does not carry the message, and its content cannot be
+// fetched again without the message content intent.
+type pendingMessage struct {
+	reminder Reminder
+	created  time.Time
+}
+
+// pendingMessages maps "userID:messageID" to a pendingMessage.
+var pendingMessages sync.Map
+
+// sourceLink returns the jump link to the message r was set on, or "" if it
+// was not set from the context menu.
+func (r Reminder) sourceLink() string {
+	if r.SourceMessageID == "" {
+		return ""
+	}
+	guildID := r.SourceGuildID
+	if guildID == "" {
+		guildID = "@me"
+	}
+	return fmt.Sprintf("https://discord.com/channels/%s/%s/%s", guildID, r.SourceChannelID, r.SourceMessageID)
+}
+
+// parseMessageLink splits a jump link as produced by sourceLink.
+func parseMessageLink(link string) (guildID, channelID, messageID string, ok bool) {
+	m := messageLinkRe.FindStringSubmatch(link)
+	if m == nil {
+		return "", "", "", false
+	}
+	if m[1] != "@me" {
+		guildID = m[1]
+	}
+	return guildID, m[2], m[3], true
+}
+
+// quoteExcerpt shortens a message for quoting and defuses its mentions so a
+// delivery does not ping everyone the original message did.
+func quoteExcerpt(m *discordgo.Message) string {
+	text := strings.TrimSpace(m.Content)
+	if text == "" && len(m.Attachments) > 0 {
+		text = "(attachment)"
+	}
+	if text == "" && len(m.Embeds) > 0 {
+		text = "(embed)"
+	}
+	if runes := []rune(text); len(runes) > maxQuoteLength {
+		text = string(runes[:maxQuoteLength-1]) + "…"
+	}
+	return strings.ReplaceAll(text, "@", "@\u200b")
+}
+
+// quoteBlock renders text as a Discord block quote ending in a newline, or
+// "" when there is nothing to quote.
+func quoteBlock(text string) string {
+	if text == "" {
+		return ""
+	}
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		b.WriteString("> " + line + "\n")
+	}
+	return b.String()
+}
+
+// handleRemindMessageCommand opens the modal asking when to be reminded about
+// the selected message.
+func handleRemindMessageCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	var m *discordgo.Message
+	if data.Resolved != nil {
+		m = data.Resolved.Messages[data.TargetID]
+	}
+	if m == nil {
+		respondEphemeral(s, i, "Could not read that message")
+		return
+	}
+
+	author := "someone"
+	if m.Author != nil {
+		author = m.Author.Username
+	}
+	// Threads are channels of their own, so i.ChannelID already points the
+	// link and the delivery at the thread.
+	r := Reminder{
+		Message:         "message from " + author,
+		SourceGuildID:   i.GuildID,
+		SourceChannelID: i.ChannelID,
+		SourceMessageID: m.ID,
+		Quote:           quoteExcerpt(m),
+	}
+
+	now := time.Now()
+	pendingMessages.Range(func(key, value any) bool {
+		if now.Sub(value.(pendingMessage).created) > pendingMessageTTL {
+			pendingMessages.Delete(key)
+		}
+		return true
+	})
+	pendingMessages.Store(interactionUserID(i)+":"+m.ID, pendingMessage{reminder: r, created: now})
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: fmt.Sprintf("%s:%s", customIDRemindMessage, m.ID),
+			Title:    "Remind me about this message",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    snoozeInputID,
+						Label:       "Remind me",
+						Style:       discordgo.TextInputShort,
+						Placeholder: "2h, tomorrow 9am, friday 14:00",
+						Required:    true,
+						MaxLength:   100,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    noteInputID,
+						Label:       "Note",
+						Style:       discordgo.TextInputParagraph,
+						Placeholder: "What to follow up on",
+						MaxLength:   500,
+					},
+				}},
+			},
+		},
+	})
+}
+
+// handleRemindMessageSubmit sets the reminder once the modal is submitted.
+func handleRemindMessageSubmit(s *discordgo.Session, i *discordgo.InteractionCreate, messageID string) {
+	values := make(map[string]string)
+	for _, c := range i.ModalSubmitData().Components {
+		row, ok := c.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, rc := range row.Components {
+			if input, ok := rc.(*discordgo.TextInput); ok {
+				values[input.CustomID] = strings.TrimSpace(input.Value)
+			}
+		}
+	}
+
+	v, ok := pendingMessages.LoadAndDelete(interactionUserID(i) + ":" + messageID)
+	if !ok {
+		respondEphemeral(s, i, "This form has expired. Please use the menu on the message again")
+		return
+	}
+	r := v.(pendingMessage).reminder
+	if note := values[noteInputID]; note != "" {
+		r.Message = note
+	}
+
+	runRemindAbout(newInteractionContext(s, i), values[snoozeInputID], r, nil)
+}
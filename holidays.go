@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// holidayCalendars maps a calendar name, the file name without extension, to
+// the dates it marks as holidays. It is loaded once at startup.
+var holidayCalendars = map[string]holidayCalendar{}
+
+var holidayDateRe = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// holidayCalendar is a set of dates keyed as "2006-01-02".
+type holidayCalendar map[string]bool
+
+// contains reports whether t falls on a holiday, judged by t's own date.
+func (c holidayCalendar) contains(t time.Time) bool {
+	return c[t.Format("2006-01-02")]
+}
+
+// loadHolidayCalendars reads every .ics, .yaml and .yml file in dir. A missing
+// directory just means no calendars are available.
+func loadHolidayCalendars(dir string) (map[string]holidayCalendar, error) {
+	calendars := make(map[string]holidayCalendar)
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return calendars, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		var parse func(io.Reader) (holidayCalendar, error)
+		switch ext {
+		case ".ics":
+			parse = parseICSHolidays
+		case ".yaml", ".yml":
+			parse = parseYAMLHolidays
+		default:
+			continue
+		}
+
+		f, err := os.Open(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		cal, err := parse(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+
+		name := strings.ToLower(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		calendars[name] = cal
+		log.Printf("Loaded holiday calendar %s with %d dates", name, len(cal))
+	}
+
+	return calendars, nil
+}
+
+// parseICSHolidays collects the dates covered by each VEVENT's DTSTART and,
+// for multi-day events, DTEND. Recurrence rules are not expanded.
+func parseICSHolidays(r io.Reader) (holidayCalendar, error) {
+	cal := make(holidayCalendar)
+
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		// Folded lines continue the previous one after a leading space.
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var start, end time.Time
+	inEvent := false
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(strings.ToUpper(name), ";")
+
+		switch name {
+		case "BEGIN":
+			if value == "VEVENT" {
+				inEvent = true
+				start, end = time.Time{}, time.Time{}
+			}
+		case "DTSTART", "DTEND":
+			if !inEvent {
+				continue
+			}
+			if len(value) < 8 {
+				return nil, fmt.Errorf("invalid %s %q", name, value)
+			}
+			t, err := time.Parse("20060102", value[:8])
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q", name, value)
+			}
+			if name == "DTSTART" {
+				start = t
+			} else {
+				end = t
+			}
+		case "END":
+			if value != "VEVENT" || !inEvent {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				continue
+			}
+			cal[start.Format("2006-01-02")] = true
+			// DTEND is exclusive for all-day events.
+			for d := start.AddDate(0, 0, 1); d.Before(end); d = d.AddDate(0, 0, 1) {
+				cal[d.Format("2006-01-02")] = true
+			}
+		}
+	}
+
+	return cal, nil
+}
+
+// parseYAMLHolidays reads the simple YAML layouts used for holiday lists:
+// list items that are dates ("- 2025-01-01"), list items with a date key
+// ("- date: 2025-01-01") or maps keyed by date ("2025-01-01: New Year"),
+// optionally nested under a top-level key such as "holidays:".
+func parseYAMLHolidays(r io.Reader) (holidayCalendar, error) {
+	cal := make(holidayCalendar)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimPrefix(line, "- "))
+		if line == "" || line == "-" {
+			continue
+		}
+
+		key, value, hasValue := strings.Cut(line, ":")
+		key = unquoteYAML(key)
+		value = unquoteYAML(value)
+
+		switch {
+		case !hasValue && holidayDateRe.MatchString(key):
+			cal[key] = true
+		case hasValue && holidayDateRe.MatchString(key):
+			cal[key] = true
+		case hasValue && key == "date" && holidayDateRe.MatchString(value):
+			cal[value] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for date := range cal {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return nil, fmt.Errorf("invalid date %q", date)
+		}
+	}
+	return cal, nil
+}
+
+func unquoteYAML(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		s = s[1 : len(s)-1]
+	}
+	return s
+}
+
+// holidayCalendarNames lists the loaded calendars for error messages.
+func holidayCalendarNames() string {
+	if len(holidayCalendars) == 0 {
+		return "none are configured"
+	}
+	names := make([]string, 0, len(holidayCalendars))
+	for name := range holidayCalendars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "available: " + strings.Join(names, ", ")
+}
@@ -5,20 +5,23 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/joho/godotenv"
-	_ "github.com/mattn/go-sqlite3"
 	"github.com/robfig/cron/v3"
 )
 
@@ -29,22 +32,112 @@ type Reminder struct {
 	Message   string
 	DueTime   time.Time
 	CronExpr  sql.NullString
+	Paused    bool
+	// PausedUntil ends a pause automatically. It is zero for pauses that
+	// last until !resume.
+	PausedUntil time.Time
+	// DeliveredAt is set once a one-shot reminder has fired; it is kept
+	// until its snooze window passes.
+	DeliveredAt time.Time
+	// NagInterval re-pings a one-shot reminder until its owner presses Done.
+	// NagCount is how many re-pings have been sent.
+	NagInterval time.Duration
+	NagCount    int
+	// Mentions are the users and roles pinged on delivery, e.g. "<@123>" or
+	// "<@&456>". When empty the owner is pinged.
+	Mentions []string
+	// Until ends a recurring reminder after the given time. MaxFires ends it
+	// after that many occurrences; FireCount is how many have been sent.
+	Until     time.Time
+	MaxFires  int
+	FireCount int
+	// Timezone is the IANA zone a recurring reminder's cron fields are
+	// evaluated in. When empty the owner's timezone is used.
+	Timezone string
+	// SkipCount is how many upcoming occurrences of a recurring reminder
+	// are suppressed.
+	SkipCount int
+	// HolidayCalendar names a calendar from holidayCalendars whose dates a
+	// recurring reminder skips.
+	HolidayCalendar string
+	// Urgent reminders ignore their owner's quiet hours.
+	Urgent bool
+	// QueuedCount is how many occurrences of a recurring reminder are being
+	// held back by quiet hours. A one-shot reminder is held until
+	// DeferredUntil instead, keeping its due time.
+	QueuedCount   int
+	DeferredUntil time.Time
+	// Warnings are the lead times at which heads-up notices are sent before
+	// a delivery, longest first. WarnedAt is when the last one was due.
+	Warnings []time.Duration
+	WarnedAt time.Time
+	// DM delivers to the owner's direct messages instead of ChannelID.
+	DM bool
+	// SourceGuildID, SourceChannelID and SourceMessageID identify the
+	// message a reminder was set on from the context menu, and Quote is an
+	// excerpt of it. SourceGuildID is empty for messages in DMs.
+	SourceGuildID   string
+	SourceChannelID string
+	SourceMessageID string
+	Quote           string
+}
+
+// deliveryTime returns when a one-shot reminder fires: its due time, or the
+// end of the quiet hours it is being held for.
+func (r Reminder) deliveryTime() time.Time {
+	if !r.DeferredUntil.IsZero() {
+		return r.DeferredUntil
+	}
+	return r.DueTime
+}
+
+// recipients returns the mentions a delivery should ping.
+func (r Reminder) recipients() string {
+	if len(r.Mentions) == 0 {
+		return fmt.Sprintf("<@%s>", r.UserID)
+	}
+	return strings.Join(r.Mentions, " ")
+}
+
+// reminderJSON is the wire format used by !export and !import.
+type reminderJSON struct {
+	ID           int      `json:"id"`
+	ChannelID    string   `json:"channel_id"`
+	UserID       string   `json:"user_id"`
+	Message      string   `json:"message"`
+	DueTime      string   `json:"due_time,omitempty"`
+	CronExpr     string   `json:"cron_expr,omitempty"`
+	Paused       bool     `json:"paused,omitempty"`
+	Nag          string   `json:"nag,omitempty"`
+	Mentions     []string `json:"mentions,omitempty"`
+	Until        string   `json:"until,omitempty"`
+	Times        int      `json:"times,omitempty"`
+	Timezone     string   `json:"timezone,omitempty"`
+	Skip         int      `json:"skip,omitempty"`
+	PausedUntil  string   `json:"paused_until,omitempty"`
+	SkipHolidays string   `json:"skip_holidays,omitempty"`
+	Urgent       bool     `json:"urgent,omitempty"`
+	Warn         []string `json:"warn,omitempty"`
+	DM           bool     `json:"dm,omitempty"`
+	Source       string   `json:"source,omitempty"`
+	Quote        string   `json:"quote,omitempty"`
 }
 
 func (r Reminder) MarshalJSON() ([]byte, error) {
-	type Alias struct {
-		ID        int    `json:"id"`
-		ChannelID string `json:"channel_id"`
-		UserID    string `json:"user_id"`
-		Message   string `json:"message"`
-		DueTime   string `json:"due_time,omitempty"`
-		CronExpr  string `json:"cron_expr,omitempty"`
-	}
-	a := Alias{
-		ID:        r.ID,
-		ChannelID: r.ChannelID,
-		UserID:    r.UserID,
-		Message:   r.Message,
+	a := reminderJSON{
+		ID:           r.ID,
+		ChannelID:    r.ChannelID,
+		UserID:       r.UserID,
+		Message:      r.Message,
+		Paused:       r.Paused,
+		Mentions:     r.Mentions,
+		Timezone:     r.Timezone,
+		Skip:         r.SkipCount,
+		SkipHolidays: r.HolidayCalendar,
+		Urgent:       r.Urgent,
+		DM:           r.DM,
+		Source:       r.sourceLink(),
+		Quote:        r.Quote,
 	}
 	if !r.DueTime.IsZero() {
 		a.DueTime = r.DueTime.Format(time.RFC3339)
@@ -52,43 +145,162 @@ func (r Reminder) MarshalJSON() ([]byte, error) {
 	if r.CronExpr.Valid {
 		a.CronExpr = r.CronExpr.String
 	}
+	if r.NagInterval > 0 {
+		a.Nag = r.NagInterval.String()
+	}
+	if !r.Until.IsZero() {
+		a.Until = r.Until.Format(time.RFC3339)
+	}
+	if !r.PausedUntil.IsZero() {
+		a.PausedUntil = r.PausedUntil.Format(time.RFC3339)
+	}
+	for _, d := range r.Warnings {
+		a.Warn = append(a.Warn, d.String())
+	}
+	if r.MaxFires > 0 {
+		// Exported as the occurrences still to come so an import picks up
+		// where the original left off.
+		a.Times = r.MaxFires - r.FireCount
+	}
 	return json.Marshal(a)
 
 }
 
+func (r *Reminder) UnmarshalJSON(data []byte) error {
+	var a reminderJSON
+	if err := json.Unmarshal(data, &a); err != nil {
+		return err
+	}
+	*r = Reminder{
+		ID:              a.ID,
+		ChannelID:       a.ChannelID,
+		UserID:          a.UserID,
+		Message:         a.Message,
+		Paused:          a.Paused,
+		Mentions:        a.Mentions,
+		Timezone:        a.Timezone,
+		SkipCount:       a.Skip,
+		HolidayCalendar: a.SkipHolidays,
+		Urgent:          a.Urgent,
+		DM:              a.DM,
+		Quote:           a.Quote,
+	}
+	if a.DueTime != "" {
+		t, err := time.Parse(time.RFC3339, a.DueTime)
+		if err != nil {
+			return err
+		}
+		r.DueTime = t
+	}
+	if a.CronExpr != "" {
+		r.CronExpr = sql.NullString{Valid: true, String: a.CronExpr}
+	}
+	if a.Source != "" {
+		guildID, channelID, messageID, ok := parseMessageLink(a.Source)
+		if !ok {
+			return fmt.Errorf("invalid source message link %q", a.Source)
+		}
+		r.SourceGuildID, r.SourceChannelID, r.SourceMessageID = guildID, channelID, messageID
+	}
+	if a.Nag != "" {
+		d, err := time.ParseDuration(a.Nag)
+		if err != nil {
+			return err
+		}
+		r.NagInterval = d
+	}
+	if a.Until != "" {
+		t, err := time.Parse(time.RFC3339, a.Until)
+		if err != nil {
+			return err
+		}
+		r.Until = t
+	}
+	if a.PausedUntil != "" {
+		t, err := time.Parse(time.RFC3339, a.PausedUntil)
+		if err != nil {
+			return err
+		}
+		r.PausedUntil = t
+	}
+	if len(a.Warn) > 0 {
+		warnings, err := parseWarnings(strings.Join(a.Warn, ","))
+		if err != nil {
+			return err
+		}
+		r.Warnings = warnings
+	}
+	r.MaxFires = a.Times
+	return nil
+}
+
 var (
-	db             *sql.DB
-	reminders      map[int]*time.Timer
-	cronScheduler  *cron.Cron
-	cronEntries    sync.Map
-	pausedEntries  sync.Map
-	snoozedEntries sync.Map
+	store             Store
+	reminderScheduler *heapScheduler
+	cronScheduler     *cron.Cron
+	cronEntries       sync.Map
+	pausedEntries     sync.Map
+	missedGrace       = 24 * time.Hour
+	snoozeWindow      = 24 * time.Hour
+)
+
+// lateThreshold is how far past its due time a reminder must be delivered
+// before it is marked as late.
+const lateThreshold = time.Minute
+
+const (
+	// minNagInterval keeps --nag from flooding a channel.
+	minNagInterval = time.Minute
+	// maxNagPings caps how many times a reminder re-pings before giving up.
+	maxNagPings = 10
 )
 
 const (
 	customIDStopRecurring  = "stopRecurring"
 	customIDPauseRecurring = "pauseRecurring"
 	customIDSnoozeReminder = "snoozeReminder"
+	customIDSnoozeCustom   = "snoozeCustom"
+	customIDNagDone        = "nagDone"
+	customIDSkipRecurring  = "skipRecurring"
+	customIDRemindMessage  = "remindMessage"
+
+	snoozeCustomValue = "custom"
+	snoozeInputID     = "when"
+	noteInputID       = "note"
 )
 
+// maxImportSize caps the size of a file accepted by !import.
+const maxImportSize = 1 << 20
+
+// attachmentClient downloads !import files. Its timeout keeps a stalled
+// download from holding the command forever.
+var attachmentClient = &http.Client{Timeout: 30 * time.Second}
+
+// maxMessageLength is the most characters Discord accepts in a message.
+const maxMessageLength = 2000
+
 var (
 	parser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
 )
 
-func init() {
-	loc, err := time.LoadLocation("Asia/Jakarta")
-	if err != nil {
-		log.Fatal(err)
-	}
-	time.Local = loc
-}
-
 func main() {
 	err := godotenv.Load()
 	if err != nil {
 		log.Println("No .env file found, using system environment variables")
 	}
 
+	// DEFAULT_TIMEZONE becomes time.Local and applies to users who haven't
+	// picked a zone with !timezone.
+	defaultTZ := os.Getenv("DEFAULT_TIMEZONE")
+	if defaultTZ == "" {
+		defaultTZ = "Asia/Jakarta"
+	}
+	loc, err := time.LoadLocation(defaultTZ)
+	if err != nil {
+		log.Fatal("Invalid DEFAULT_TIMEZONE:", err)
+	}
+	time.Local = loc
+
 	token := os.Getenv("DISCORD_TOKEN")
 	if token == "" {
 		log.Fatal("DISCORD_TOKEN environment variable is not set")
@@ -99,28 +311,48 @@ func main() {
 		log.Fatal("Error creating Discord session:", err)
 	}
 
-	db, err = sql.Open("sqlite3", "/app/data/reminders.db")
+	if v := os.Getenv("MISSED_REMINDER_GRACE"); v != "" {
+		missedGrace, err = parseDuration(v)
+		if err != nil {
+			log.Fatal("Invalid MISSED_REMINDER_GRACE:", err)
+		}
+	}
+
+	if v := os.Getenv("SNOOZE_WINDOW"); v != "" {
+		snoozeWindow, err = parseDuration(v)
+		if err != nil {
+			log.Fatal("Invalid SNOOZE_WINDOW:", err)
+		}
+	}
+
+	holidayDir := os.Getenv("HOLIDAY_CALENDARS_DIR")
+	if holidayDir == "" {
+		holidayDir = "/app/data/holidays"
+	}
+	holidayCalendars, err = loadHolidayCalendars(holidayDir)
 	if err != nil {
-		log.Fatal("Error opening database:", err)
+		log.Fatal("Error loading holiday calendars:", err)
 	}
-	defer db.Close()
 
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS reminders (
-        id INTEGER PRIMARY KEY AUTOINCREMENT,
-        channel_id TEXT,
-        user_id TEXT,
-        message TEXT,
-        due_time DATETIME,
-        cron_expr TEXT
-    )`)
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		dsn = "/app/data/reminders.db"
+	}
+	store, err = newStore(os.Getenv("DATABASE_DRIVER"), dsn)
 	if err != nil {
-		log.Fatal("Error creating table:", err)
+		log.Fatal("Error opening database:", err)
 	}
+	defer store.Close()
 
-	reminders = make(map[int]*time.Timer)
 	cronScheduler = cron.New(cron.WithSeconds())
 	cronEntries = sync.Map{}
 
+	// Handlers can schedule reminders as soon as the session opens, so the
+	// scheduler must exist first. It only starts firing once Start is called.
+	reminderScheduler = newHeapScheduler(store.ListDueReminders, func(r Reminder) {
+		fireReminder(dg, r)
+	})
+
 	dg.AddHandler(messageCreate)
 	dg.AddHandler(interactionCreate)
 
@@ -130,13 +362,43 @@ func main() {
 	}
 
 	scheduleAllReminders(dg)
+	purgeDeliveredReminders()
+	_, err = cronScheduler.AddFunc("@every 10m", purgeDeliveredReminders)
+	if err != nil {
+		log.Fatal("Error scheduling cleanup:", err)
+	}
+	purgeEndedReminders()
+	_, err = cronScheduler.AddFunc("@every 10m", purgeEndedReminders)
+	if err != nil {
+		log.Fatal("Error scheduling cleanup:", err)
+	}
+	resumeExpiredPauses(dg)
+	_, err = cronScheduler.AddFunc("@every 1m", func() { resumeExpiredPauses(dg) })
+	if err != nil {
+		log.Fatal("Error scheduling pause checks:", err)
+	}
+	_, err = cronScheduler.AddFunc("@every 1m", func() { deliverQueuedReminders(dg) })
+	if err != nil {
+		log.Fatal("Error scheduling quiet hours checks:", err)
+	}
+	_, err = cronScheduler.AddFunc("@every 15s", func() { sendDueWarnings(dg) })
+	if err != nil {
+		log.Fatal("Error scheduling warnings:", err)
+	}
+	reminderScheduler.Start()
 	cronScheduler.Start()
 
+	err = registerSlashCommands(dg, os.Getenv("DISCORD_GUILD_ID"))
+	if err != nil {
+		log.Println("Error registering slash commands:", err)
+	}
+
 	fmt.Println("Bot is running. Press CTRL-C to exit.")
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
 	<-sc
 
+	reminderScheduler.Stop()
 	cronScheduler.Stop()
 	dg.Close()
 }
@@ -157,19 +419,68 @@ func messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 	case "!recurring":
 		handleRecurringCommand(s, m, parts)
 	case "!list":
-		listReminders(s, m)
+		listReminders(newMessageContext(s, m))
 	case "!delete":
 		handleDeleteCommand(s, m, parts)
+	case "!pause":
+		handlePauseCommand(s, m, parts)
 	case "!resume":
 		handleResumeCommand(s, m, parts)
+	case "!edit":
+		handleEditCommand(s, m, parts)
 	case "!export":
-		handleExportCommand(s, m)
+		handleExportCommand(newMessageContext(s, m))
+	case "!import":
+		handleImportCommand(s, m)
+	case "!timezone":
+		handleTimezoneCommand(s, m, parts)
+	case "!preview":
+		handlePreviewCommand(s, m, parts)
+	case "!quiet":
+		handleQuietCommand(s, m, parts)
+	case "!dm":
+		handleDMCommand(s, m, parts)
+	case "!skip":
+		handleSkipCommand(s, m, parts)
+	}
+}
+
+// commandContext carries what a command needs to run and answer, so the same
+// logic serves both ! prefix messages and slash commands.
+type commandContext struct {
+	s         *discordgo.Session
+	guildID   string
+	channelID string
+	userID    string
+	reply     func(content string)
+}
+
+func newMessageContext(s *discordgo.Session, m *discordgo.MessageCreate) *commandContext {
+	return &commandContext{
+		s:         s,
+		guildID:   m.GuildID,
+		channelID: m.ChannelID,
+		userID:    m.Author.ID,
+		reply: func(content string) {
+			s.ChannelMessageSend(m.ChannelID, content)
+		},
 	}
 }
 
 func handleRemindCommand(s *discordgo.Session, m *discordgo.MessageCreate, parts []string) {
+	ctx := newMessageContext(s, m)
+
+	options, parts := extractOptions(parts, []string{"nag", "warn"}, "urgent", "dm")
+
+	// Leading mentions name who gets pinged instead of the author.
+	mentions, rest := extractMentions(parts[1:])
+	if len(mentions) > 0 {
+		options["mentions"] = strings.Join(mentions, " ")
+		parts = append(parts[:1], rest...)
+	}
+
 	if len(parts) < 3 {
-		s.ChannelMessageSend(m.ChannelID, "Usage: !remind <duration/time> <message> or !remind `<time>` <message>")
+		ctx.reply("Usage: !remind [--nag <interval>] [--warn <lead,...>] [--urgent] [--dm] [@user|@role ...] <duration/time> <message> or !remind `<time>` <message> (e.g. !remind tomorrow at 9am standup)")
 		return
 	}
 
@@ -180,59 +491,258 @@ func handleRemindCommand(s *discordgo.Session, m *discordgo.MessageCreate, parts
 	if strings.HasPrefix(parts[1], "`") {
 		args := parseBacktickArgs(strings.Join(parts[1:], " "))
 		if len(args) < 2 {
-			s.ChannelMessageSend(m.ChannelID, "Invalid command format. Please provide both time and message.")
+			ctx.reply("Invalid command format. Please provide both time and message.")
 			return
 		}
 		timeStr = args[0]
 		message = strings.Join(args[1:], " ")
 	} else {
-		timeStr = parts[1]
-		message = strings.Join(parts[2:], " ")
+		timeStr, message = splitReminderTime(parts[1:], userLocation(ctx.userID))
+		if timeStr == "" {
+			timeStr = parts[1]
+			message = strings.Join(parts[2:], " ")
+		}
 	}
 
-	now := time.Now()
-	var dueTime time.Time
+	runRemind(ctx, timeStr, message, options)
+}
 
-	// First, try to parse as duration
-	duration, err := parseDuration(timeStr)
-	if err == nil {
-		dueTime = now.Add(duration)
-	} else {
-		// If not a duration, try to parse as a specific time
-		dueTime, err = parseFlexibleTime(timeStr)
+// reminderOptions holds the --flags accepted by !remind.
+type reminderOptions struct {
+	Nag      time.Duration
+	Mentions []string
+	Urgent   bool
+	Warnings []time.Duration
+	DM       bool
+}
+
+var mentionRe = regexp.MustCompile(`^<@([!&]?)(\d+)>$`)
+
+// extractMentions splits leading user and role mentions off words.
+func extractMentions(words []string) ([]string, []string) {
+	i := 0
+	for i < len(words) && mentionRe.MatchString(words[i]) {
+		i++
+	}
+	return words[:i], words[i:]
+}
+
+// validateMentions checks that every mentioned user is a member and every
+// mentioned role exists in the guild, and returns them in canonical form.
+func validateMentions(s *discordgo.Session, guildID string, mentions []string) ([]string, error) {
+	if len(mentions) == 0 {
+		return nil, nil
+	}
+	if guildID == "" {
+		return nil, fmt.Errorf("mentions only work in server channels")
+	}
+
+	valid := make([]string, 0, len(mentions))
+	for _, mention := range mentions {
+		match := mentionRe.FindStringSubmatch(mention)
+		if match == nil {
+			return nil, fmt.Errorf("%q is not a user or role mention", mention)
+		}
+
+		if match[1] == "&" {
+			if _, err := s.State.Role(guildID, match[2]); err != nil {
+				roles, err := s.GuildRoles(guildID)
+				if err != nil {
+					return nil, fmt.Errorf("could not check roles: %v", err)
+				}
+				found := slices.ContainsFunc(roles, func(r *discordgo.Role) bool { return r.ID == match[2] })
+				if !found {
+					return nil, fmt.Errorf("role %s does not exist in this server", match[2])
+				}
+			}
+			mention = "<@&" + match[2] + ">"
+		} else {
+			if _, err := s.GuildMember(guildID, match[2]); err != nil {
+				return nil, fmt.Errorf("user %s is not a member of this server", match[2])
+			}
+			mention = "<@" + match[2] + ">"
+		}
+
+		if !slices.Contains(valid, mention) {
+			valid = append(valid, mention)
+		}
+	}
+	return valid, nil
+}
+
+// extractOptions pulls the "--name value" options in names and the valueless
+// "--flag" switches in flags that directly follow the command word, returning
+// them by name with flags set to "true". Options are only read up to the
+// first other word, so a message mentioning "--warn" keeps it.
+func extractOptions(words []string, names []string, flags ...string) (map[string]string, []string) {
+	options := make(map[string]string)
+	if len(words) == 0 {
+		return options, words
+	}
+
+	i := 1
+	for i < len(words) {
+		name, ok := strings.CutPrefix(words[i], "--")
+		if ok && slices.Contains(flags, name) {
+			options[name] = "true"
+			i++
+		} else if ok && i+1 < len(words) && slices.Contains(names, name) {
+			options[name] = words[i+1]
+			i += 2
+		} else {
+			break
+		}
+	}
+
+	return options, append([]string{words[0]}, words[i:]...)
+}
+
+func parseReminderOptions(values map[string]string) (reminderOptions, error) {
+	var opts reminderOptions
+
+	if v, ok := values["nag"]; ok {
+		d, err := parseDuration(v)
 		if err != nil {
-			s.ChannelMessageSend(m.ChannelID, "Invalid time format. Use a duration (e.g., 5m, 2h, 1d) or a specific time (e.g., 2023-05-20T15:04:05).")
-			return
+			return opts, fmt.Errorf("invalid --nag interval %q", v)
+		}
+		if d < minNagInterval {
+			return opts, fmt.Errorf("--nag interval must be at least %s", minNagInterval)
+		}
+		opts.Nag = d
+	}
+
+	if v, ok := values["mentions"]; ok {
+		opts.Mentions = strings.Fields(v)
+	}
+
+	opts.Urgent = values["urgent"] == "true"
+	opts.DM = values["dm"] == "true"
+	if opts.DM && len(opts.Mentions) > 0 {
+		return opts, fmt.Errorf("--dm cannot be combined with mentions")
+	}
+
+	if v, ok := values["warn"]; ok {
+		warnings, err := parseWarnings(v)
+		if err != nil {
+			return opts, err
 		}
+		opts.Warnings = warnings
+	}
+
+	return opts, nil
+}
+
+func runRemind(ctx *commandContext, timeStr, message string, values map[string]string) {
+	runRemindAbout(ctx, timeStr, Reminder{Message: message}, values)
+}
+
+// runRemindAbout sets a one-shot reminder from base, which carries the
+// message and, for reminders set from the context menu, its source.
+func runRemindAbout(ctx *commandContext, timeStr string, base Reminder, values map[string]string) {
+	now := time.Now()
+
+	opts, err := parseReminderOptions(values)
+	if err != nil {
+		ctx.reply("Error: " + err.Error())
+		return
+	}
+
+	mentions, err := validateMentions(ctx.s, ctx.guildID, opts.Mentions)
+	if err != nil {
+		ctx.reply("Error: " + err.Error())
+		return
+	}
+
+	dueTime, err := parseReminderTime(timeStr, userLocation(ctx.userID))
+	if err != nil {
+		ctx.reply("Invalid time format. Use a duration (e.g., 5m, 2h, 1d), a specific time (e.g., 2023-05-20T15:04:05) or a phrase (e.g., tomorrow at 9am, next friday 14:00).")
+		return
 	}
 
 	// Check if the due time is in the future
 	if dueTime.Before(now) {
-		s.ChannelMessageSend(m.ChannelID, "Error: Reminder time must be in the future.")
+		ctx.reply("Error: Reminder time must be in the future.")
 		return
 	}
 
-	reminder := Reminder{
-		ChannelID: m.ChannelID,
-		UserID:    m.Author.ID,
-		Message:   message,
-		DueTime:   dueTime,
-	}
+	reminder := base
+	reminder.ChannelID = ctx.channelID
+	reminder.UserID = ctx.userID
+	reminder.DueTime = dueTime
+	reminder.NagInterval = opts.Nag
+	reminder.Mentions = mentions
+	reminder.Urgent = opts.Urgent
+	reminder.Warnings = opts.Warnings
+	reminder.DM = opts.DM
 
 	id, err := saveReminder(reminder)
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Error setting reminder: "+err.Error())
+		ctx.reply("Error setting reminder: " + err.Error())
 		return
 	}
 
-	scheduleReminder(s, id, reminder)
+	scheduleReminder(id, reminder)
+
+	reply := fmt.Sprintf("Reminder set for <t:%d:F>, <t:%d:R> (ID: %d)", dueTime.Unix(), dueTime.Unix(), id)
+	if len(mentions) > 0 {
+		reply += fmt.Sprintf(", pinging %d mentions", len(mentions))
+	}
+	if opts.Nag > 0 {
+		reply += fmt.Sprintf(", nagging every %s until you press Done", opts.Nag)
+	}
+	if opts.Urgent {
+		reply += ", ignoring quiet hours"
+	}
+	if len(opts.Warnings) > 0 {
+		reply += ", with heads-ups " + formatWarnings(opts.Warnings) + " before"
+	}
+	if opts.DM {
+		reply += ", delivered by DM"
+	}
+	ctx.reply(reply)
+}
+
+// parseReminderTime resolves a user supplied time, trying a duration from now
+// first and falling back to a specific time in loc.
+func parseReminderTime(timeStr string, loc *time.Location) (time.Time, error) {
+	if duration, err := parseDuration(timeStr); err == nil {
+		return time.Now().Add(duration), nil
+	}
+	if t, err := parseFlexibleTime(timeStr, loc); err == nil {
+		return t, nil
+	}
+	return parseNaturalTime(timeStr, time.Now().In(loc))
+}
+
+// splitReminderTime finds the longest leading run of words that parses as a
+// time, so phrases like "tomorrow at 9am" work without backticks. It returns
+// an empty time string if no prefix parses.
+func splitReminderTime(words []string, loc *time.Location) (string, string) {
+	n := leadingTimeWords(words, loc)
+	if n == 0 {
+		return "", ""
+	}
+	return strings.Join(words[:n], " "), strings.Join(words[n:], " ")
+}
 
-	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Reminder set for <t:%d:F>, <t:%d:R> (ID: %d)", dueTime.Unix(), dueTime.Unix(), id))
+// leadingTimeWords returns how many leading words form the longest parseable
+// time, always leaving at least one word behind.
+func leadingTimeWords(words []string, loc *time.Location) int {
+	n := len(words) - 1
+	if n > maxNaturalTokens {
+		n = maxNaturalTokens
+	}
+	for ; n > 0; n-- {
+		if _, err := parseReminderTime(strings.Join(words[:n], " "), loc); err == nil {
+			return n
+		}
+	}
+	return 0
 }
 
-func parseFlexibleTime(timeStr string) (time.Time, error) {
+func parseFlexibleTime(timeStr string, loc *time.Location) (time.Time, error) {
 	// First, try to parse as AM/PM format
-	if t, err := parseAMPM(timeStr); err == nil {
+	if t, err := parseAMPM(timeStr, loc); err == nil {
 		return t, nil
 	}
 
@@ -249,11 +759,11 @@ func parseFlexibleTime(timeStr string) (time.Time, error) {
 	}
 
 	for _, format := range formats {
-		if t, err := time.ParseInLocation(format, timeStr, time.Local); err == nil {
+		if t, err := time.ParseInLocation(format, timeStr, loc); err == nil {
 			// If only time is provided (not date), set it to today or tomorrow
 			if len(timeStr) <= 8 { // Assuming time formats like "15:04:05" or "15:04"
-				now := time.Now()
-				t = time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local)
+				now := time.Now().In(loc)
+				t = time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc)
 				if t.Before(now) {
 					t = t.AddDate(0, 0, 1) // Set to tomorrow if the time today has already passed
 				}
@@ -265,7 +775,7 @@ func parseFlexibleTime(timeStr string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("unable to parse time: %s", timeStr)
 }
 
-func parseAMPM(timeStr string) (time.Time, error) {
+func parseAMPM(timeStr string, loc *time.Location) (time.Time, error) {
 	re := regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(?::(\d{2}))?\s*(am|pm)$`)
 	matches := re.FindStringSubmatch(strings.ToLower(timeStr))
 
@@ -283,8 +793,8 @@ func parseAMPM(timeStr string) (time.Time, error) {
 		hour = 0
 	}
 
-	now := time.Now()
-	t := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, second, 0, time.Local)
+	now := time.Now().In(loc)
+	t := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, second, 0, loc)
 
 	if t.Before(now) {
 		t = t.AddDate(0, 0, 1) // Set to tomorrow if the time today has already passed
@@ -293,76 +803,317 @@ func parseAMPM(timeStr string) (time.Time, error) {
 	return t, nil
 }
 
+const recurringUsage = "Usage: !recurring [--warn <lead,...>] [--dm] `seconds minutes hours day_of_month month day_of_week` [until <time>] [times <n>] [tz <zone>] [skip-holidays <calendar>] [urgent] <message>\n" +
+	"or: !recurring [--warn <lead,...>] [--dm] every <schedule> [until <time>] [times <n>] [tz <zone>] [skip-holidays <calendar>] [urgent] <message>"
+
 func handleRecurringCommand(s *discordgo.Session, m *discordgo.MessageCreate, parts []string) {
+	ctx := newMessageContext(s, m)
+
+	flags, parts := extractOptions(parts, []string{"warn"}, "dm")
+
 	fullCommand := strings.Join(parts[1:], " ")
 
-	args := parseBacktickArgs(fullCommand)
+	var args []string
+	if len(parts) > 1 && isRecurrencePhrase(parts[1]) {
+		n := leadingRecurrenceWords(parts[1:])
+		if n == 0 {
+			ctx.reply("Could not understand the schedule. Try e.g. every weekday at 9:30, every 2 hours or every first monday of the month at 10.")
+			return
+		}
+		args = append([]string{strings.Join(parts[1:1+n], " ")}, parts[1+n:]...)
+	} else {
+		args = parseBacktickArgs(fullCommand)
+	}
 
 	if len(args) < 2 {
-		s.ChannelMessageSend(m.ChannelID, "Usage: !recurring `seconds minutes hours day_of_month month day_of_week` <message>")
+		ctx.reply(recurringUsage)
 		return
 	}
 
-	cronExpr := args[0]
-	message := strings.Join(args[1:], " ")
-
-	_, err := parser.Parse(cronExpr)
+	options, rest, err := extractRecurringClauses(args[1:], userLocation(ctx.userID))
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Invalid cron expression. Please check your syntax.")
+		ctx.reply("Error: " + err.Error())
+		return
+	}
+	for name, value := range flags {
+		options[name] = value
+	}
+	if len(rest) == 0 {
+		ctx.reply(recurringUsage)
+		return
+	}
+
+	runRecurring(ctx, args[0], strings.Join(rest, " "), options)
+}
+
+// extractRecurringClauses consumes leading "until <time>", "times <n>", "tz
+// <zone>", "skip-holidays <calendar>" and "urgent" clauses, returning them by
+// name along with the words that follow.
+func extractRecurringClauses(words []string, loc *time.Location) (map[string]string, []string, error) {
+	options := make(map[string]string)
+
+	for len(words) > 0 {
+		switch strings.ToLower(words[0]) {
+		case "until":
+			n := leadingTimeWords(words[1:], loc)
+			if n == 0 {
+				return nil, nil, fmt.Errorf("could not understand the time after \"until\"")
+			}
+			options["until"] = strings.Join(words[1:1+n], " ")
+			words = words[1+n:]
+		case "times":
+			if len(words) < 2 {
+				return nil, nil, fmt.Errorf("\"times\" needs a number")
+			}
+			options["times"] = words[1]
+			words = words[2:]
+		case "tz":
+			if len(words) < 2 {
+				return nil, nil, fmt.Errorf("\"tz\" needs a timezone name")
+			}
+			options["tz"] = words[1]
+			words = words[2:]
+		case "skip-holidays":
+			if len(words) < 2 {
+				return nil, nil, fmt.Errorf("\"skip-holidays\" needs a calendar name")
+			}
+			options["skip-holidays"] = words[1]
+			words = words[2:]
+		case "urgent":
+			options["urgent"] = "true"
+			words = words[1:]
+		default:
+			return options, words, nil
+		}
+	}
+
+	return options, words, nil
+}
+
+func runRecurring(ctx *commandContext, cronExpr, message string, values map[string]string) {
+	cronExpr, zone := splitCronZone(cronExpr)
+	if v, ok := values["tz"]; ok {
+		if zone != "" && zone != v {
+			ctx.reply("Error: the cron expression and the tz option name different timezones.")
+			return
+		}
+		zone = v
+	}
+
+	loc := userLocation(ctx.userID)
+	if zone != "" {
+		zoneLoc, err := time.LoadLocation(zone)
+		if err != nil {
+			ctx.reply("Unknown timezone. Use an IANA name such as Europe/Berlin or America/New_York.")
+			return
+		}
+		loc = zoneLoc
+		zone = zoneLoc.String()
+	}
+
+	phrase := ""
+	if isRecurrencePhrase(cronExpr) {
+		compiled, err := compileRecurrence(cronExpr)
+		if err != nil {
+			ctx.reply("Could not understand the schedule: " + err.Error())
+			return
+		}
+		phrase, cronExpr = cronExpr, compiled
+	}
+
+	if _, err := parseCron(cronExpr, loc); err != nil {
+		ctx.reply("Invalid cron expression: " + err.Error())
 		return
 	}
 
 	reminder := Reminder{
-		ChannelID: m.ChannelID,
-		UserID:    m.Author.ID,
+		ChannelID: ctx.channelID,
+		UserID:    ctx.userID,
 		Message:   message,
 		CronExpr: sql.NullString{
 			Valid:  true,
 			String: cronExpr,
 		},
+		Timezone: zone,
 	}
 
-	id, err := saveReminder(reminder)
-	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Error setting recurring reminder: "+err.Error())
-		return
+	if v, ok := values["until"]; ok {
+		until, err := parseReminderTime(v, loc)
+		if err != nil {
+			ctx.reply("Invalid until time. Use a duration (e.g., 2w), a specific time (e.g., 2023-05-20) or a phrase (e.g., next friday).")
+			return
+		}
+		if until.Before(time.Now()) {
+			ctx.reply("Error: Until time must be in the future.")
+			return
+		}
+		reminder.Until = until
 	}
 
-	scheduleRecurringReminder(s, id, reminder)
-
-	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Recurring reminder set with ID: %d", id))
-}
+	if v, ok := values["times"]; ok {
+		times, err := strconv.Atoi(v)
+		if err != nil || times < 1 {
+			ctx.reply("Error: times must be a positive number.")
+			return
+		}
+		reminder.MaxFires = times
+	}
 
-func parseDuration(s string) (time.Duration, error) {
-	if d, err := time.ParseDuration(s); err == nil {
-		return d, nil
+	if v, ok := values["skip-holidays"]; ok {
+		name := strings.ToLower(v)
+		if _, ok := holidayCalendars[name]; !ok {
+			ctx.reply(fmt.Sprintf("Unknown holiday calendar %q (%s)", v, holidayCalendarNames()))
+			return
+		}
+		reminder.HolidayCalendar = name
 	}
 
-	var valueStr, unit string
-	for i, r := range s {
-		if r < '0' || r > '9' {
-			valueStr = s[:i]
-			unit = s[i:]
-			break
+	reminder.Urgent = values["urgent"] == "true"
+	reminder.DM = values["dm"] == "true"
+
+	if v, ok := values["warn"]; ok {
+		warnings, err := parseWarnings(v)
+		if err != nil {
+			ctx.reply("Error: " + err.Error())
+			return
 		}
+		reminder.Warnings = warnings
 	}
 
-	if valueStr == "" || unit == "" {
-		return 0, fmt.Errorf("invalid duration format")
+	// Holidays can leave nothing of a sparse schedule.
+	if _, err := reminderSchedule(reminder); err != nil {
+		ctx.reply("Invalid schedule: " + err.Error())
+		return
 	}
 
-	value, err := strconv.Atoi(valueStr)
+	id, err := saveReminder(reminder)
 	if err != nil {
-		return 0, err
+		ctx.reply("Error setting recurring reminder: " + err.Error())
+		return
 	}
 
-	switch strings.ToLower(unit) {
-	case "m", "min", "mins", "minute", "minutes":
-		return time.Duration(value) * time.Minute, nil
-	case "h", "hr", "hrs", "hour", "hours":
-		return time.Duration(value) * time.Hour, nil
-	case "d", "day", "days":
-		return time.Duration(value) * 24 * time.Hour, nil
+	scheduleRecurringReminder(ctx.s, id, reminder)
+
+	reply := fmt.Sprintf("Recurring reminder set with ID: %d", id)
+	if phrase != "" {
+		reply += fmt.Sprintf(" (%s = `%s`)", phrase, cronExpr)
+	}
+	if !reminder.Until.IsZero() {
+		reply += fmt.Sprintf(", until <t:%d:F>", reminder.Until.Unix())
+	}
+	if reminder.MaxFires > 0 {
+		reply += fmt.Sprintf(", %d occurrences", reminder.MaxFires)
+	}
+	reply += "\nRuns " + describeCron(cronExpr)
+	if zone != "" {
+		reply += " (" + zone + ")"
+	}
+	if reminder.HolidayCalendar != "" {
+		reply += ", except holidays in " + reminder.HolidayCalendar
+	}
+	if reminder.Urgent {
+		reply += ", ignoring quiet hours"
+	}
+	if len(reminder.Warnings) > 0 {
+		reply += ", with heads-ups " + formatWarnings(reminder.Warnings) + " before"
+	}
+	if reminder.DM {
+		reply += ", delivered by DM"
+	}
+	if schedule, err := reminderSchedule(reminder); err == nil {
+		if times := nextFireTimes(schedule, time.Now(), previewCount); len(times) > 0 {
+			reply += ". Next times:\n" + formatFireTimes(times, "\n")
+		}
+	}
+	ctx.reply(reply)
+}
+
+// parseCron parses a cron expression whose fields are evaluated in loc. An
+// explicit CRON_TZ= or TZ= prefix in the expression takes precedence. The
+// Quartz-style "L" and "#" day fields are also accepted; see splitDayFilter.
+func parseCron(expr string, loc *time.Location) (cron.Schedule, error) {
+	spec, filter, err := splitDayFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := parser.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	if spec, ok := schedule.(*cron.SpecSchedule); ok {
+		if !strings.HasPrefix(expr, "CRON_TZ=") && !strings.HasPrefix(expr, "TZ=") {
+			spec.Location = loc
+		}
+		if filter != nil {
+			schedule = &filteredSchedule{schedule: spec, loc: spec.Location, match: filter}
+		}
+	}
+	return schedule, checkScheduleFires(schedule)
+}
+
+// checkScheduleFires rejects schedules that never fire, such as "0 0 0 30 2
+// *", which the cron library would otherwise accept and silently never run.
+func checkScheduleFires(schedule cron.Schedule) error {
+	if schedule.Next(time.Now()).IsZero() {
+		return fmt.Errorf("schedule never fires")
+	}
+	return nil
+}
+
+// reminderSchedule returns the schedule of a recurring reminder in its
+// timezone, leaving out the dates of its holiday calendar.
+func reminderSchedule(r Reminder) (cron.Schedule, error) {
+	loc := reminderLocation(r)
+	schedule, err := parseCron(r.CronExpr.String, loc)
+	if err != nil {
+		return nil, err
+	}
+	if r.HolidayCalendar == "" {
+		return schedule, nil
+	}
+	cal, ok := holidayCalendars[r.HolidayCalendar]
+	if !ok {
+		// Still fire rather than silently stop; !list reports the missing
+		// calendar.
+		log.Printf("Holiday calendar %q of reminder %d is not loaded", r.HolidayCalendar, r.ID)
+		return schedule, nil
+	}
+	filtered := &filteredSchedule{schedule: schedule, loc: loc, match: func(t time.Time) bool { return !cal.contains(t) }}
+	return filtered, checkScheduleFires(filtered)
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+
+	var valueStr, unit string
+	for i, r := range s {
+		if r < '0' || r > '9' {
+			valueStr = s[:i]
+			unit = s[i:]
+			break
+		}
+	}
+
+	if valueStr == "" || unit == "" {
+		return 0, fmt.Errorf("invalid duration format")
+	}
+
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		return 0, err
+	}
+
+	switch strings.ToLower(unit) {
+	case "s", "sec", "secs", "second", "seconds":
+		return time.Duration(value) * time.Second, nil
+	case "m", "min", "mins", "minute", "minutes":
+		return time.Duration(value) * time.Minute, nil
+	case "h", "hr", "hrs", "hour", "hours":
+		return time.Duration(value) * time.Hour, nil
+	case "d", "day", "days":
+		return time.Duration(value) * 24 * time.Hour, nil
 	case "w", "wk", "wks", "week", "weeks":
 		return time.Duration(value) * 7 * 24 * time.Hour, nil
 	default:
@@ -400,146 +1151,456 @@ func parseBacktickArgs(s string) []string {
 	return args
 }
 
-func scheduleReminder(s *discordgo.Session, id int, r Reminder) {
-	duration := time.Until(r.DueTime)
-	timer := time.AfterFunc(duration, func() {
-		msg := &discordgo.MessageSend{
-			Content: fmt.Sprintf("<@%s> Reminder: %s", r.UserID, r.Message),
-			Components: []discordgo.MessageComponent{
-				discordgo.ActionsRow{
-					Components: []discordgo.MessageComponent{
-						discordgo.SelectMenu{
-							CustomID:    fmt.Sprintf("%s:%d", customIDSnoozeReminder, id),
-							Placeholder: "Snooze for...",
-							Options: []discordgo.SelectMenuOption{
-								{Label: "5 minutes", Value: "5m"},
-								{Label: "10 minutes", Value: "10m"},
-								{Label: "15 minutes", Value: "15m"},
-								{Label: "30 minutes", Value: "30m"},
-								{Label: "60 minutes", Value: "60m"},
-							},
+func scheduleReminder(id int, r Reminder) {
+	r.ID = id
+	reminderScheduler.Add(r)
+}
+
+// fireReminder is called by reminderScheduler when a one-shot reminder is
+// due. Reminders missed while the bot was down are delivered late within
+// missedGrace and reported as expired after it.
+func fireReminder(s *discordgo.Session, r Reminder) {
+	// Re-read the row so edits and deletions since it was queued win.
+	id := r.ID
+	r, err := store.GetReminder(id)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Error loading reminder %d: %v", id, err)
+		}
+		return
+	}
+	if !r.DeliveredAt.IsZero() {
+		return
+	}
+
+	if !r.Urgent && time.Since(r.deliveryTime()) <= missedGrace {
+		if end, quiet := quietUntil(r.UserID, time.Now()); quiet {
+			deferReminder(r, end)
+			return
+		}
+	}
+
+	// A held reminder counts as late from the end of its quiet hours but is
+	// always delivered with its original due time.
+	late := time.Since(r.deliveryTime())
+	switch {
+	case late > missedGrace:
+		expireReminder(s, r.ID, r)
+	case late > lateThreshold || !r.DeferredUntil.IsZero():
+		deliverReminder(s, r.ID, r, true)
+	default:
+		deliverReminder(s, r.ID, r, false)
+	}
+}
+
+// deferReminder holds a one-shot reminder that came due during its owner's
+// quiet hours until the end of them. Its due time is kept for the delivery.
+func deferReminder(r Reminder, until time.Time) {
+	r.DeferredUntil = until
+	if err := store.DeferReminder(r.ID, until); err != nil {
+		log.Printf("Error deferring reminder %d for quiet hours: %v", r.ID, err)
+		return
+	}
+	scheduleReminder(r.ID, r)
+}
+
+// sendDelivery posts msg for r, to its owner's DMs when r or the owner's
+// default asks for that. If the DM cannot be sent it falls back to r's
+// channel with a notice.
+func sendDelivery(s *discordgo.Session, r Reminder, msg *discordgo.MessageSend) {
+	if len(r.Mentions) == 0 && (r.DM || userPrefersDM(r.UserID)) {
+		dm, err := s.UserChannelCreate(r.UserID)
+		if err == nil {
+			_, err = s.ChannelMessageSendComplex(dm.ID, msg)
+		}
+		if err == nil {
+			return
+		}
+		log.Printf("Error sending reminder %d by DM, falling back to its channel: %v", r.ID, err)
+		msg.Content += "\n(I couldn't DM you, so this was posted here. Allow DMs from server members to get reminders privately.)"
+	}
+	s.ChannelMessageSendComplex(r.ChannelID, msg)
+}
+
+// deliverReminder posts a one-shot reminder with its snooze menu and marks it
+// delivered, keeping it around for snoozeWindow so the menu keeps working.
+// Late deliveries mention the original due time.
+func deliverReminder(s *discordgo.Session, id int, r Reminder, late bool) {
+	content := fmt.Sprintf("%s Reminder: %s", r.recipients(), r.Message)
+	if late {
+		content = fmt.Sprintf("%s Late reminder (was due <t:%d:F>, <t:%d:R>): %s", r.recipients(), r.DueTime.Unix(), r.DueTime.Unix(), r.Message)
+	}
+	if r.NagCount > 0 {
+		content += fmt.Sprintf(" (ping %d/%d)", r.NagCount+1, maxNagPings+1)
+	}
+	if !r.DeferredUntil.IsZero() {
+		content += " (held during quiet hours)"
+		r.DeferredUntil = time.Time{}
+	}
+	if r.SourceMessageID != "" {
+		content += "\n" + quoteBlock(r.Quote) + r.sourceLink()
+	}
+
+	msg := &discordgo.MessageSend{
+		Content: content,
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.SelectMenu{
+						CustomID:    fmt.Sprintf("%s:%d", customIDSnoozeReminder, id),
+						Placeholder: "Snooze for...",
+						Options: []discordgo.SelectMenuOption{
+							{Label: "5 minutes", Value: "5m"},
+							{Label: "10 minutes", Value: "10m"},
+							{Label: "15 minutes", Value: "15m"},
+							{Label: "30 minutes", Value: "30m"},
+							{Label: "60 minutes", Value: "60m"},
+							{Label: "Tomorrow morning", Value: "tomorrow morning"},
+							{Label: "Next week", Value: "next week"},
+							{Label: "Custom…", Value: snoozeCustomValue},
 						},
 					},
 				},
 			},
+		},
+	}
+	if r.NagInterval > 0 {
+		msg.Components = append(msg.Components, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Done",
+					Style:    discordgo.SuccessButton,
+					CustomID: fmt.Sprintf("%s:%d", customIDNagDone, id),
+				},
+			},
+		})
+	}
+	sendDelivery(s, r, msg)
+
+	r.ID = id
+
+	// Nagging reminders stay pending and come back after NagInterval until
+	// Done is pressed or the ping cap is reached.
+	if r.NagInterval > 0 && r.NagCount < maxNagPings {
+		r.NagCount++
+		r.DueTime = time.Now().Add(r.NagInterval)
+		if err := store.RecordNag(id, r.DueTime, r.NagCount); err != nil {
+			log.Printf("Error rescheduling nag for reminder %d: %v", id, err)
+			return
 		}
-		s.ChannelMessageSendComplex(r.ChannelID, msg)
+		scheduleReminder(id, r)
+		return
+	}
 
-		snoozedEntries.Store(id, r)
-		time.AfterFunc(5*time.Minute, func() { snoozedEntries.Delete(id) })
-		deleteReminder(id)
+	// Only the delivery time is written so an edit made while the message
+	// was being sent is not overwritten.
+	if err := store.MarkReminderDelivered(id, time.Now()); err != nil {
+		log.Printf("Error marking reminder %d delivered: %v", id, err)
+	}
+}
+
+func handleNagDoneInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, id int) {
+	ok, err := isReminderOwner(id, interactionUserID(i))
+	if err != nil {
+		respondEphemeral(s, i, "Reminder not found")
+		return
+	}
+	if !ok {
+		respondEphemeral(s, i, "Only the owner can mark this reminder done")
+		return
+	}
+
+	r, err := store.GetReminder(id)
+	if err != nil {
+		respondEphemeral(s, i, "Error loading reminder: "+err.Error())
+		return
+	}
+	if !r.DeliveredAt.IsZero() {
+		respondEphemeral(s, i, fmt.Sprintf("Reminder %d is already done", id))
+		return
+	}
+
+	unscheduleReminder(id)
+	if err := store.MarkReminderDelivered(id, time.Now()); err != nil {
+		respondEphemeral(s, i, "Error updating reminder: "+err.Error())
+		return
+	}
+
+	respondEphemeral(s, i, fmt.Sprintf("Reminder %d acknowledged after %d re-pings", id, r.NagCount))
+}
+
+// purgeDeliveredReminders deletes delivered reminders whose snooze window
+// has passed.
+func purgeDeliveredReminders() {
+	if err := store.PurgeDeliveredReminders(time.Now().Add(-snoozeWindow)); err != nil {
+		log.Printf("Error purging delivered reminders: %v", err)
+	}
+}
+
+// purgeEndedReminders deletes recurring reminders past their until time.
+// Those that fire are removed on their last occurrence, but paused ones and
+// ones whose schedule never came round again would otherwise linger.
+func purgeEndedReminders() {
+	list, err := store.ListEndedReminders(time.Now())
+	if err != nil {
+		log.Printf("Error fetching ended reminders: %v", err)
+		return
+	}
+	for _, r := range list {
+		if err := deleteReminder(r.ID); err != nil {
+			log.Printf("Error deleting ended reminder %d: %v", r.ID, err)
+		}
+	}
+}
+
+// expireReminder drops a reminder that was missed by more than missedGrace and
+// tells its owner instead of delivering it late.
+func expireReminder(s *discordgo.Session, id int, r Reminder) {
+	sendDelivery(s, r, &discordgo.MessageSend{
+		Content: fmt.Sprintf("<@%s> Reminder %d expired while the bot was offline (was due <t:%d:F>): %s", r.UserID, id, r.DueTime.Unix(), r.Message),
 	})
-	reminders[id] = timer
+	deleteReminder(id)
 }
 
 func scheduleRecurringReminder(s *discordgo.Session, id int, r Reminder) {
-	schedule, err := parser.Parse(r.CronExpr.String)
+	schedule, err := reminderSchedule(r)
 	if err != nil {
 		log.Printf("Error parsing cron expression: %v", err)
 		return
 	}
 
 	entryID := cronScheduler.Schedule(schedule, cron.FuncJob(func() {
-		if val, ok := pausedEntries.Load(id); ok {
-			if paused, ok := val.(bool); ok && paused {
+		fireRecurringReminder(s, id, schedule)
+	}))
+
+	cronEntries.Store(id, entryID)
+}
+
+// fireRecurringReminder handles one occurrence of a recurring reminder,
+// honouring pauses, skips and its owner's quiet hours.
+func fireRecurringReminder(s *discordgo.Session, id int, schedule cron.Schedule) {
+	if val, ok := pausedEntries.Load(id); ok {
+		if paused, ok := val.(bool); ok && paused {
+			// A timed pause that ended since the last check is lifted here
+			// so this occurrence is not lost.
+			r, err := store.GetReminder(id)
+			if err != nil || r.PausedUntil.IsZero() || time.Now().Before(r.PausedUntil) {
 				return
 			}
+			autoResumeReminder(s, r)
 		}
-		s.ChannelMessageSendComplex(r.ChannelID, &discordgo.MessageSend{
-			Content: fmt.Sprintf("<@%s> Recurring Reminder (ID: %d): %s", r.UserID, id, r.Message),
-			Components: []discordgo.MessageComponent{
-				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
-					discordgo.Button{
-						Label:    "Stop",
-						Style:    discordgo.DangerButton,
-						CustomID: fmt.Sprintf("%s:%d", customIDStopRecurring, id),
-					},
-					discordgo.Button{
-						Label:    "Pause",
-						Style:    discordgo.PrimaryButton,
-						CustomID: fmt.Sprintf("%s:%d", customIDPauseRecurring, id),
-					},
-				}},
-			},
-		})
-	}))
+	}
 
-	cronEntries.Store(id, entryID)
+	r, err := store.GetReminder(id)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Error loading reminder %d: %v", id, err)
+		}
+		return
+	}
+
+	now := time.Now()
+	if !r.Until.IsZero() && now.After(r.Until) {
+		deleteReminder(id)
+		return
+	}
+
+	if r.SkipCount > 0 {
+		if _, err := store.AddReminderSkips(id, -1); err != nil {
+			log.Printf("Error recording skipped occurrence of reminder %d: %v", id, err)
+		}
+		if !r.Until.IsZero() && schedule.Next(now).After(r.Until) {
+			deleteReminder(id)
+		}
+		return
+	}
+
+	if !r.Urgent {
+		if _, quiet := quietUntil(r.UserID, now); quiet {
+			// Held until deliverQueuedReminders sees quiet hours end.
+			if err := store.QueueReminderOccurrence(id); err != nil {
+				log.Printf("Error queueing reminder %d for quiet hours: %v", id, err)
+			}
+			return
+		}
+	}
+
+	deliverRecurringReminder(s, r, schedule, 1, "")
 }
 
-func scheduleAllReminders(s *discordgo.Session) {
-	rows, err := db.Query("SELECT id, channel_id, user_id, message, due_time, cron_expr FROM reminders")
+// deliverQueuedReminders sends the recurring occurrences held back by quiet
+// hours once their owners' quiet hours have ended, one message per reminder.
+func deliverQueuedReminders(s *discordgo.Session) {
+	list, err := store.ListQueuedReminders()
 	if err != nil {
-		log.Printf("Error fetching reminders: %v", err)
+		log.Printf("Error fetching queued reminders: %v", err)
 		return
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var r Reminder
-		var dueTimeStr sql.NullString
-		err := rows.Scan(&r.ID, &r.ChannelID, &r.UserID, &r.Message, &dueTimeStr, &r.CronExpr)
+	now := time.Now()
+	for _, r := range list {
+		if r.Paused {
+			continue
+		}
+		if _, quiet := quietUntil(r.UserID, now); quiet {
+			continue
+		}
+		schedule, err := reminderSchedule(r)
 		if err != nil {
-			log.Printf("Error scanning reminder: %v", err)
+			log.Printf("Error parsing cron expression of reminder %d: %v", r.ID, err)
 			continue
 		}
+		deliverRecurringReminder(s, r, schedule, r.QueuedCount, fmt.Sprintf(" (held during quiet hours, %d occurrence(s))", r.QueuedCount))
+	}
+}
+
+// deliverRecurringReminder sends one message for fires occurrences of r
+// with note appended, counts them, and removes r once its until time or
+// occurrence limit is reached.
+func deliverRecurringReminder(s *discordgo.Session, r Reminder, schedule cron.Schedule, fires int, note string) {
+	id := r.ID
+	now := time.Now()
+
+	r.FireCount += fires
+	r.QueuedCount = 0
+	last := (r.MaxFires > 0 && r.FireCount >= r.MaxFires) ||
+		(!r.Until.IsZero() && schedule.Next(now).After(r.Until))
+
+	content := fmt.Sprintf("%s Recurring Reminder (ID: %d): %s%s", r.recipients(), id, r.Message, note)
+	if last {
+		content += " (final occurrence)"
+	}
+
+	sendDelivery(s, r, &discordgo.MessageSend{
+		Content: content,
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Stop",
+					Style:    discordgo.DangerButton,
+					CustomID: fmt.Sprintf("%s:%d", customIDStopRecurring, id),
+				},
+				discordgo.Button{
+					Label:    "Pause",
+					Style:    discordgo.PrimaryButton,
+					CustomID: fmt.Sprintf("%s:%d", customIDPauseRecurring, id),
+				},
+				discordgo.Button{
+					Label:    "Skip next",
+					Style:    discordgo.SecondaryButton,
+					CustomID: fmt.Sprintf("%s:%d", customIDSkipRecurring, id),
+				},
+			}},
+		},
+	})
+
+	if last {
+		deleteReminder(id)
+		return
+	}
+	if err := store.RecordReminderFires(id, fires); err != nil {
+		log.Printf("Error recording occurrence of reminder %d: %v", id, err)
+	}
+}
+
+// rescheduleRecurringReminder replaces the cron entry of a recurring reminder,
+// e.g. after it was edited or its owner changed timezone.
+func rescheduleRecurringReminder(s *discordgo.Session, id int, r Reminder) {
+	unscheduleReminder(id)
+	scheduleRecurringReminder(s, id, r)
+}
+
+// rescheduleReminder replaces the pending delivery of a one-shot reminder.
+func rescheduleReminder(id int, r Reminder) {
+	unscheduleReminder(id)
+	scheduleReminder(id, r)
+}
+
+// scheduleAllReminders registers stored recurring reminders with the cron
+// scheduler. One-shot reminders are picked up by reminderScheduler itself.
+func scheduleAllReminders(s *discordgo.Session) {
+	list, err := store.ListReminders()
+	if err != nil {
+		log.Printf("Error fetching reminders: %v", err)
+		return
+	}
 
+	for _, r := range list {
 		if r.CronExpr.Valid && r.CronExpr.String != "" {
-			scheduleRecurringReminder(s, r.ID, r)
-		} else if dueTimeStr.Valid {
-			r.DueTime, err = time.Parse(time.RFC3339, dueTimeStr.String)
-			if err != nil {
-				log.Printf("Error parsing due time: %v", err)
-				continue
-			}
-			if time.Now().Before(r.DueTime) {
-				scheduleReminder(s, r.ID, r)
-			} else {
-				deleteReminder(r.ID)
+			if r.Paused {
+				pausedEntries.Store(r.ID, true)
 			}
+			scheduleRecurringReminder(s, r.ID, r)
 		}
 	}
 }
 
 func saveReminder(r Reminder) (int, error) {
-	var result sql.Result
-	var err error
+	return store.SaveReminder(r)
+}
 
-	if r.CronExpr.Valid && r.CronExpr.String != "" {
-		result, err = db.Exec("INSERT INTO reminders (channel_id, user_id, message, cron_expr) VALUES (?, ?, ?, ?)",
-			r.ChannelID, r.UserID, r.Message, r.CronExpr)
-	} else {
-		result, err = db.Exec("INSERT INTO reminders (channel_id, user_id, message, due_time) VALUES (?, ?, ?, ?)",
-			r.ChannelID, r.UserID, r.Message, r.DueTime.Format(time.RFC3339))
+// pauseRecurringReminder pauses a recurring reminder until the given time,
+// or indefinitely when until is zero.
+func pauseRecurringReminder(id int, until time.Time) error {
+	err := store.SetReminderPaused(id, true, until)
+	if err != nil {
+		return err
 	}
+	pausedEntries.Store(id, true)
+	return nil
+}
 
+func resumeRecurringReminder(id int) error {
+	err := store.SetReminderPaused(id, false, time.Time{})
 	if err != nil {
-		return 0, err
+		return err
 	}
+	pausedEntries.Delete(id)
+	return nil
+}
 
-	id, err := result.LastInsertId()
+// resumeExpiredPauses lifts timed pauses whose end has passed.
+func resumeExpiredPauses(s *discordgo.Session) {
+	list, err := store.ListExpiredPauses(time.Now())
 	if err != nil {
-		return 0, err
+		log.Printf("Error fetching expired pauses: %v", err)
+		return
+	}
+	for _, r := range list {
+		autoResumeReminder(s, r)
 	}
-
-	return int(id), nil
 }
 
-func pauseRecurringReminder(id int) {
-	pausedEntries.Store(id, true)
-}
+// autoResumeReminder resumes r after its timed pause and announces it in the
+// reminder's channel. Only the first caller for a given pause posts.
+func autoResumeReminder(s *discordgo.Session, r Reminder) {
+	if _, loaded := pausedEntries.LoadAndDelete(r.ID); !loaded {
+		return
+	}
+	if err := store.SetReminderPaused(r.ID, false, time.Time{}); err != nil {
+		log.Printf("Error resuming reminder %d: %v", r.ID, err)
+		pausedEntries.Store(r.ID, true)
+		return
+	}
 
-func resumeRecurringReminder(id int) {
-	pausedEntries.Delete(id)
+	content := fmt.Sprintf("<@%s> Recurring reminder %d resumed after its pause ended: %s", r.UserID, r.ID, r.Message)
+	if schedule, err := reminderSchedule(r); err == nil {
+		next := schedule.Next(time.Now())
+		content += fmt.Sprintf(" (next: <t:%d:F>)", next.Unix())
+	}
+	s.ChannelMessageSend(r.ChannelID, content)
 }
 
-func getReminderUserID(id int) (string, error) {
-	var userID string
-	err := db.QueryRow("SELECT user_id FROM reminders WHERE id = ?", id).Scan(&userID)
+func isReminderPaused(id int) (bool, error) {
+	r, err := store.GetReminder(id)
 	if err != nil {
-		return "", err
+		return false, err
 	}
-	return userID, nil
+	return r.Paused, nil
+}
+
+func getReminderUserID(id int) (string, error) {
+	return store.GetReminderUserID(id)
 }
 
 func isReminderOwner(id int, userID string) (bool, error) {
@@ -550,16 +1611,24 @@ func isReminderOwner(id int, userID string) (bool, error) {
 	return owner == userID, nil
 }
 
+// deleteReminder removes a reminder and its schedule. Pending advance
+// warnings live on the row, so they are cancelled with it.
 func deleteReminder(id int) error {
-	_, err := db.Exec("DELETE FROM reminders WHERE id = ?", id)
+	err := store.DeleteReminder(id)
 	if err != nil {
 		return err
 	}
 
-	if timer, exists := reminders[id]; exists {
-		timer.Stop()
-		delete(reminders, id)
-	}
+	unscheduleReminder(id)
+	pausedEntries.Delete(id)
+
+	return nil
+}
+
+// unscheduleReminder drops the pending delivery or cron entry of a reminder
+// without touching the database.
+func unscheduleReminder(id int) {
+	reminderScheduler.Remove(id)
 
 	if entryIDInterface, ok := cronEntries.Load(id); ok {
 		entryID, ok := entryIDInterface.(cron.EntryID)
@@ -568,197 +1637,713 @@ func deleteReminder(id int) error {
 		}
 		cronEntries.Delete(id)
 	}
+}
 
-	pausedEntries.Delete(id)
+func handleDeleteCommand(s *discordgo.Session, m *discordgo.MessageCreate, parts []string) {
+	ctx := newMessageContext(s, m)
 
-	return nil
+	if len(parts) != 2 {
+		ctx.reply("Usage: !delete <id>")
+		return
+	}
+
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		ctx.reply("Invalid reminder ID")
+		return
+	}
+
+	runDelete(ctx, id)
 }
 
-func handleDeleteCommand(s *discordgo.Session, m *discordgo.MessageCreate, parts []string) {
+func runDelete(ctx *commandContext, id int) {
+	ok, err := isReminderOwner(id, ctx.userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.reply("Reminder not found")
+		} else {
+			ctx.reply("Error checking reminder ownership: " + err.Error())
+		}
+		return
+	}
+
+	if !ok {
+		ctx.reply("You can only delete your own reminders")
+		return
+	}
+
+	err = deleteReminder(id)
+	if err != nil {
+		ctx.reply("Error deleting reminder: " + err.Error())
+		return
+	}
+
+	ctx.reply(fmt.Sprintf("Reminder %d deleted", id))
+}
+
+func handlePauseCommand(s *discordgo.Session, m *discordgo.MessageCreate, parts []string) {
+	ctx := newMessageContext(s, m)
+
+	if len(parts) < 2 || len(parts) == 3 {
+		ctx.reply("Usage: !pause <id> [for <duration>|until <time>]")
+		return
+	}
+
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		ctx.reply("Invalid reminder ID")
+		return
+	}
+
+	values := make(map[string]string)
+	if len(parts) > 2 {
+		keyword := strings.ToLower(parts[2])
+		if keyword != "for" && keyword != "until" {
+			ctx.reply("Usage: !pause <id> [for <duration>|until <time>]")
+			return
+		}
+		values[keyword] = strings.Join(parts[3:], " ")
+	}
+
+	runPause(ctx, id, values)
+}
+
+// runPause pauses a recurring reminder, for a duration or until a time when
+// values holds "for" or "until", and indefinitely otherwise.
+func runPause(ctx *commandContext, id int, values map[string]string) {
+	ok, err := isReminderOwner(id, ctx.userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.reply("Reminder not found")
+		} else {
+			ctx.reply("Error checking reminder: " + err.Error())
+		}
+		return
+	}
+
+	if !ok {
+		ctx.reply("You can only pause your own reminders")
+		return
+	}
+
+	r, err := store.GetReminder(id)
+	if err != nil {
+		ctx.reply("Error loading reminder: " + err.Error())
+		return
+	}
+	if !r.CronExpr.Valid || r.CronExpr.String == "" {
+		ctx.reply("Only recurring reminders can be paused")
+		return
+	}
+
+	var until time.Time
+	loc := userLocation(ctx.userID)
+	if v, ok := values["for"]; ok {
+		d, err := parseNaturalDuration(v)
+		if err != nil || d <= 0 {
+			ctx.reply("Invalid duration. Use e.g. 2h, 3d or 1 week 3 days.")
+			return
+		}
+		until = time.Now().Add(d)
+	} else if v, ok := values["until"]; ok {
+		until, err = parseReminderTime(v, loc)
+		if err != nil {
+			ctx.reply("Invalid time format. Use a specific time (e.g., 2023-05-20) or a phrase (e.g., next monday 9am).")
+			return
+		}
+		if !until.After(time.Now()) {
+			ctx.reply("Error: Pause end must be in the future.")
+			return
+		}
+	}
+
+	if err := pauseRecurringReminder(id, until); err != nil {
+		ctx.reply("Error pausing reminder: " + err.Error())
+		return
+	}
+
+	if until.IsZero() {
+		ctx.reply(fmt.Sprintf("Recurring reminder %d paused until you !resume it", id))
+	} else {
+		ctx.reply(fmt.Sprintf("Recurring reminder %d paused until <t:%d:F>, <t:%d:R>", id, until.Unix(), until.Unix()))
+	}
+}
+
+func handleResumeCommand(s *discordgo.Session, m *discordgo.MessageCreate, parts []string) {
+	ctx := newMessageContext(s, m)
+
 	if len(parts) != 2 {
-		s.ChannelMessageSend(m.ChannelID, "Usage: !delete <id>")
+		ctx.reply("Usage: !resume <id>")
 		return
 	}
 
 	id, err := strconv.Atoi(parts[1])
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Invalid reminder ID")
+		ctx.reply("Invalid reminder ID")
+		return
+	}
+
+	runResume(ctx, id)
+}
+
+func runResume(ctx *commandContext, id int) {
+	ok, err := isReminderOwner(id, ctx.userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.reply("Reminder not found")
+		} else {
+			ctx.reply("Error checking reminder: " + err.Error())
+		}
+		return
+	}
+
+	if !ok {
+		ctx.reply("You can only resume your own reminders")
+		return
+	}
+
+	paused, err := isReminderPaused(id)
+	if err != nil {
+		ctx.reply("Error checking reminder: " + err.Error())
+		return
+	}
+	if !paused {
+		ctx.reply("Reminder is not paused")
+		return
+	}
+
+	err = resumeRecurringReminder(id)
+	if err != nil {
+		ctx.reply("Error resuming reminder: " + err.Error())
+		return
+	}
+	ctx.reply(fmt.Sprintf("Recurring reminder %d resumed", id))
+}
+
+func handleSkipCommand(s *discordgo.Session, m *discordgo.MessageCreate, parts []string) {
+	ctx := newMessageContext(s, m)
+
+	if len(parts) != 2 && len(parts) != 3 {
+		ctx.reply("Usage: !skip <id> [n]")
+		return
+	}
+
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		ctx.reply("Invalid reminder ID")
+		return
+	}
+
+	n := 1
+	if len(parts) == 3 {
+		n, err = strconv.Atoi(parts[2])
+		if err != nil || n < 0 {
+			ctx.reply("Invalid count. Use a number of occurrences, or 0 to stop skipping.")
+			return
+		}
+	}
+
+	runSkip(ctx, id, n)
+}
+
+// runSkip suppresses n more occurrences of a recurring reminder on top of
+// any already skipped, or clears pending skips when n is 0.
+func runSkip(ctx *commandContext, id int, n int) {
+	ok, err := isReminderOwner(id, ctx.userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.reply("Reminder not found")
+		} else {
+			ctx.reply("Error checking reminder: " + err.Error())
+		}
+		return
+	}
+
+	if !ok {
+		ctx.reply("You can only skip your own reminders")
 		return
 	}
 
-	ok, err := isReminderOwner(id, m.Author.ID)
+	r, err := store.GetReminder(id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			s.ChannelMessageSend(m.ChannelID, "Reminder not found")
-		} else {
-			s.ChannelMessageSend(m.ChannelID, "Error checking reminder ownership: "+err.Error())
-		}
+		ctx.reply("Error loading reminder: " + err.Error())
+		return
+	}
+	if !r.CronExpr.Valid || r.CronExpr.String == "" {
+		ctx.reply("Only recurring reminders can be skipped")
 		return
 	}
 
-	if !ok {
-		s.ChannelMessageSend(m.ChannelID, "You can only delete your own reminders")
+	if n == 0 {
+		if err := store.SetReminderSkips(id, 0); err != nil {
+			ctx.reply("Error skipping reminder: " + err.Error())
+			return
+		}
+		ctx.reply(fmt.Sprintf("Recurring reminder %d will no longer skip any occurrences", id))
 		return
 	}
 
-	err = deleteReminder(id)
+	n, err = store.AddReminderSkips(id, n)
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Error deleting reminder: "+err.Error())
+		ctx.reply("Error skipping reminder: " + err.Error())
 		return
 	}
 
-	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Reminder %d deleted", id))
+	reply := fmt.Sprintf("Skipping the next %d occurrence(s) of recurring reminder %d", n, id)
+	if schedule, err := reminderSchedule(r); err == nil {
+		if times := nextFireTimes(schedule, time.Now(), n+1); len(times) > n {
+			reply += fmt.Sprintf("; it will next fire <t:%d:F>", times[n].Unix())
+		}
+	}
+	ctx.reply(reply)
 }
 
-func handleResumeCommand(s *discordgo.Session, m *discordgo.MessageCreate, parts []string) {
-	if len(parts) != 2 {
-		s.ChannelMessageSend(m.ChannelID, "Usage: !resume <id>")
+func handleEditCommand(s *discordgo.Session, m *discordgo.MessageCreate, parts []string) {
+	ctx := newMessageContext(s, m)
+
+	if len(parts) < 4 {
+		ctx.reply("Usage: !edit <id> <time|cron|timezone|message> <value>")
 		return
 	}
 
 	id, err := strconv.Atoi(parts[1])
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Invalid reminder ID")
+		ctx.reply("Invalid reminder ID")
 		return
 	}
 
-	ok, err := isReminderOwner(id, m.Author.ID)
+	value := strings.Join(parts[3:], " ")
+	if strings.HasPrefix(value, "`") {
+		if args := parseBacktickArgs(value); len(args) > 0 {
+			value = args[0]
+		}
+	}
+
+	runEdit(ctx, id, strings.ToLower(parts[2]), value)
+}
+
+// runEdit changes one field of a reminder in place, keeping its ID, and
+// replaces its timer or cron entry so the change takes effect immediately.
+func runEdit(ctx *commandContext, id int, field, value string) {
+	ok, err := isReminderOwner(id, ctx.userID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			s.ChannelMessageSend(m.ChannelID, "Reminder not found")
+			ctx.reply("Reminder not found")
 		} else {
-			s.ChannelMessageSend(m.ChannelID, "Error checking reminder: "+err.Error())
+			ctx.reply("Error checking reminder ownership: " + err.Error())
 		}
 		return
 	}
 
 	if !ok {
-		s.ChannelMessageSend(m.ChannelID, "You can only resume your own reminders")
+		ctx.reply("You can only edit your own reminders")
+		return
+	}
+
+	r, err := store.GetReminder(id)
+	if err != nil {
+		ctx.reply("Error loading reminder: " + err.Error())
+		return
+	}
+	recurring := r.CronExpr.Valid && r.CronExpr.String != ""
+
+	switch field {
+	case "time":
+		if recurring {
+			ctx.reply("Reminder is recurring; edit its cron instead")
+			return
+		}
+		dueTime, err := parseReminderTime(value, userLocation(ctx.userID))
+		if err != nil {
+			ctx.reply("Invalid time format. Use a duration (e.g., 5m, 2h, 1d), a specific time (e.g., 2023-05-20T15:04:05) or a phrase (e.g., tomorrow at 9am, next friday 14:00).")
+			return
+		}
+		if dueTime.Before(time.Now()) {
+			ctx.reply("Error: Reminder time must be in the future.")
+			return
+		}
+		r.DueTime = dueTime
+		r.DeliveredAt = time.Time{}
+		// A new time is no longer one held back by quiet hours.
+		r.DeferredUntil = time.Time{}
+	case "cron":
+		if !recurring {
+			ctx.reply("Reminder is not recurring; edit its time instead")
+			return
+		}
+		var zone string
+		value, zone = splitCronZone(value)
+		if zone != "" {
+			loc, err := time.LoadLocation(zone)
+			if err != nil {
+				ctx.reply("Unknown timezone. Use an IANA name such as Europe/Berlin or America/New_York.")
+				return
+			}
+			r.Timezone = loc.String()
+		}
+		if isRecurrencePhrase(value) {
+			compiled, err := compileRecurrence(value)
+			if err != nil {
+				ctx.reply("Could not understand the schedule: " + err.Error())
+				return
+			}
+			value = compiled
+		}
+		if _, err := parseCron(value, reminderLocation(r)); err != nil {
+			ctx.reply("Invalid cron expression: " + err.Error())
+			return
+		}
+		r.CronExpr.String = value
+	case "timezone":
+		if !recurring {
+			ctx.reply("Only recurring reminders have their own timezone")
+			return
+		}
+		if value == "" || strings.EqualFold(value, "default") {
+			r.Timezone = ""
+			break
+		}
+		loc, err := time.LoadLocation(value)
+		if err != nil {
+			ctx.reply("Unknown timezone. Use an IANA name such as Europe/Berlin or America/New_York.")
+			return
+		}
+		r.Timezone = loc.String()
+	case "message":
+		r.Message = value
+	default:
+		ctx.reply("Usage: !edit <id> <time|cron|timezone|message> <value>")
 		return
 	}
 
-	if paused, ok := pausedEntries.Load(id); !ok || !paused.(bool) {
-		s.ChannelMessageSend(m.ChannelID, "Reminder is not paused")
+	err = store.UpdateReminder(r)
+	if err != nil {
+		ctx.reply("Error updating reminder: " + err.Error())
 		return
 	}
+	// Heads-ups sent so far were for the old schedule, and an earlier new
+	// time must not be taken as already warned about.
+	if field != "message" && len(r.Warnings) > 0 {
+		if err := store.SetReminderWarnedAt(id, time.Time{}); err != nil {
+			log.Printf("Error resetting warnings of reminder %d: %v", id, err)
+		}
+	}
 
-	resumeRecurringReminder(id)
-	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Recurring reminder %d resumed", id))
+	if recurring {
+		rescheduleRecurringReminder(ctx.s, id, r)
+		ctx.reply(fmt.Sprintf("Recurring reminder %d updated", id))
+	} else {
+		rescheduleReminder(id, r)
+		ctx.reply(fmt.Sprintf("Reminder %d updated, due <t:%d:F>, <t:%d:R>", id, r.DueTime.Unix(), r.DueTime.Unix()))
+	}
 }
 
-func listReminders(s *discordgo.Session, m *discordgo.MessageCreate) {
-	rows, err := db.Query("SELECT id, message, due_time, cron_expr FROM reminders WHERE user_id = ?", m.Author.ID)
+func listReminders(ctx *commandContext) {
+	list, err := store.ListUserReminders(ctx.userID)
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Error fetching reminders: "+err.Error())
+		ctx.reply("Error fetching reminders: " + err.Error())
 		return
 	}
-	defer rows.Close()
 
-	var reminders strings.Builder
-	reminders.WriteString("Your reminders:\n")
+	loc := userLocation(ctx.userID)
 
-	for rows.Next() {
-		var id int
-		var message string
-		var dueTimeNullStr, cronExpr sql.NullString
-		err := rows.Scan(&id, &message, &dueTimeNullStr, &cronExpr)
-		if err != nil {
-			log.Printf("Error scanning reminder: %v", err)
-			continue
-		}
+	var reminders strings.Builder
+	reminders.WriteString(fmt.Sprintf("Your reminders (timezone: %s):\n", loc))
 
-		paused := false
-		if val, ok := pausedEntries.Load(id); ok {
-			if b, ok2 := val.(bool); ok2 {
-				paused = b
+	for _, r := range list {
+		if r.CronExpr.Valid && r.CronExpr.String != "" {
+			details := fmt.Sprintf("recurring: `%s`, %s", r.CronExpr.String, describeCron(r.CronExpr.String))
+			if r.Timezone != "" {
+				details += " (" + r.Timezone + ")"
 			}
-		}
-
-		if cronExpr.Valid && cronExpr.String != "" {
-			if paused {
-				reminders.WriteString(fmt.Sprintf("%d: %s (recurring: %s, paused)\n", id, message, cronExpr.String))
-			} else {
-				schedule, _ := parser.Parse(cronExpr.String)
-				now := time.Now()
-				next := schedule.Next(now)
-				reminders.WriteString(fmt.Sprintf("%d: %s (recurring: %s, next: <t:%d:F>, <t:%d:R>)\n", id, message, cronExpr.String, next.Unix(), next.Unix()))
+			if r.Paused && !r.PausedUntil.IsZero() {
+				details += fmt.Sprintf(", paused until <t:%d:F>", r.PausedUntil.Unix())
+			} else if r.Paused {
+				details += ", paused"
+			} else if schedule, err := reminderSchedule(r); err == nil {
+				if times := nextFireTimes(schedule, time.Now(), r.SkipCount+previewCount); len(times) > r.SkipCount {
+					details += ", next: " + formatFireTimes(times[r.SkipCount:], ", ")
+				}
 			}
-		} else if dueTimeNullStr.Valid {
-			dueTime, err := time.Parse(time.RFC3339, dueTimeNullStr.String)
-			if err != nil {
-				log.Printf("Error parsing due time: %v", err)
-				continue
+			if r.SkipCount > 0 {
+				details += fmt.Sprintf(", skipping next %d", r.SkipCount)
+			}
+			if _, ok := holidayCalendars[r.HolidayCalendar]; ok {
+				details += ", skipping holidays in " + r.HolidayCalendar
+			} else if r.HolidayCalendar != "" {
+				details += ", NOT skipping holidays: calendar " + r.HolidayCalendar + " is not loaded"
+			}
+			if r.QueuedCount > 0 {
+				details += fmt.Sprintf(", %d occurrence(s) queued for quiet hours", r.QueuedCount)
+			}
+			if len(r.Warnings) > 0 {
+				details += ", heads-ups " + formatWarnings(r.Warnings) + " before"
+			}
+			if r.DM {
+				details += ", by DM"
+			}
+			if r.MaxFires > 0 {
+				details += fmt.Sprintf(", remaining: %d", r.MaxFires-r.FireCount)
+			}
+			if !r.Until.IsZero() {
+				details += fmt.Sprintf(", until <t:%d:F>", r.Until.Unix())
+			}
+			reminders.WriteString(fmt.Sprintf("%d: %s (%s)\n", r.ID, r.Message, details))
+		} else if !r.DueTime.IsZero() {
+			message := r.Message
+			if link := r.sourceLink(); link != "" {
+				message += " " + link
+			}
+			if !r.DeferredUntil.IsZero() {
+				reminders.WriteString(fmt.Sprintf("%d: %s (was due <t:%d:F>, held for quiet hours, delivers <t:%d:F>, <t:%d:R>)\n", r.ID, message, r.DueTime.Unix(), r.DeferredUntil.Unix(), r.DeferredUntil.Unix()))
+			} else if r.NagInterval > 0 {
+				reminders.WriteString(fmt.Sprintf("%d: %s (due <t:%d:F>, <t:%d:R>, nagging every %s, %d/%d re-pings sent)\n", r.ID, message, r.DueTime.Unix(), r.DueTime.Unix(), r.NagInterval, r.NagCount, maxNagPings))
+			} else {
+				reminders.WriteString(fmt.Sprintf("%d: %s (due <t:%d:F>, <t:%d:R>)\n", r.ID, message, r.DueTime.Unix(), r.DueTime.Unix()))
 			}
-			reminders.WriteString(fmt.Sprintf("%d: %s (due <t:%d:F>, <t:%d:R>)\n", id, message, dueTime.Unix(), dueTime.Unix()))
 		}
 	}
 
 	if reminders.Len() == 0 {
-		s.ChannelMessageSend(m.ChannelID, "You have no reminders set")
+		ctx.reply("You have no reminders set")
 	} else {
-		s.ChannelMessageSend(m.ChannelID, reminders.String())
+		for _, chunk := range splitMessage(reminders.String(), maxMessageLength) {
+			ctx.reply(chunk)
+		}
 	}
 }
 
-func handleExportCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
-	data, err := exportActiveRemindersForUser(m.Author.ID)
+// splitMessage breaks content into pieces of at most limit bytes, at line
+// breaks where possible, so long listings are sent as several messages
+// instead of being rejected by Discord.
+func splitMessage(content string, limit int) []string {
+	var chunks []string
+	var chunk strings.Builder
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if chunk.Len()+len(line) > limit && chunk.Len() > 0 {
+			chunks = append(chunks, chunk.String())
+			chunk.Reset()
+		}
+		for len(line) > limit {
+			cut := limit
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			chunks = append(chunks, line[:cut])
+			line = line[cut:]
+		}
+		chunk.WriteString(line)
+	}
+	if chunk.Len() > 0 {
+		chunks = append(chunks, chunk.String())
+	}
+	return chunks
+}
+
+func handleExportCommand(ctx *commandContext) {
+	data, err := exportActiveRemindersForUser(ctx.userID)
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Error exporting reminders: "+err.Error())
+		ctx.reply("Error exporting reminders: " + err.Error())
 		return
 	}
-	dm, err := s.UserChannelCreate(m.Author.ID)
+	dm, err := ctx.s.UserChannelCreate(ctx.userID)
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Error creating DM channel: "+err.Error())
+		ctx.reply("Error creating DM channel: " + err.Error())
 		return
 	}
 
 	reader := bytes.NewReader(data)
-	_, err = s.ChannelFileSend(dm.ID, "reminders.json", reader)
+	_, err = ctx.s.ChannelFileSend(dm.ID, "reminders.json", reader)
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Error sending file via DM: "+err.Error())
+		ctx.reply("Error sending file via DM: " + err.Error())
 		return
 	}
 
-	s.ChannelMessageSend(m.ChannelID, "I've sent your reminders via DM.")
+	ctx.reply("I've sent your reminders via DM.")
 }
 
 func exportActiveRemindersForUser(userID string) ([]byte, error) {
-	rows, err := db.Query("SELECT id, channel_id, user_id, message, due_time, cron_expr FROM reminders WHERE user_id = ?", userID)
+	all, err := store.ListUserReminders(userID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
 	now := time.Now()
 	var list []Reminder
 
-	for rows.Next() {
-		if err := rows.Err(); err != nil {
-			return nil, err
+	for _, r := range all {
+		if r.CronExpr.Valid && r.CronExpr.String != "" {
+			list = append(list, r)
+		} else if r.DueTime.After(now) {
+			list = append(list, r)
 		}
+	}
+
+	return json.MarshalIndent(list, "", "  ")
+}
+
+func handleImportCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
+	ctx := newMessageContext(s, m)
+
+	if len(m.Attachments) != 1 {
+		ctx.reply("Usage: !import with the reminders.json file from !export attached")
+		return
+	}
+
+	runImport(ctx, m.Attachments[0].URL)
+}
+
+// runImport loads reminders from a file produced by !export. Every entry is
+// validated on its own and, if valid, recreated with a fresh ID owned by the
+// importing user.
+func runImport(ctx *commandContext, url string) {
+	data, err := downloadAttachment(url)
+	if err != nil {
+		ctx.reply("Error downloading file: " + err.Error())
+		return
+	}
+
+	var list []Reminder
+	if err := json.Unmarshal(data, &list); err != nil {
+		ctx.reply("Invalid reminders file: " + err.Error())
+		return
+	}
+	if len(list) == 0 {
+		ctx.reply("No reminders found in file")
+		return
+	}
+
+	now := time.Now()
+	imported := 0
 
-		var r Reminder
-		var dueTimeStr sql.NullString
-		if err := rows.Scan(&r.ID, &r.ChannelID, &r.UserID, &r.Message, &dueTimeStr, &r.CronExpr); err != nil {
-			return nil, err
+	var report strings.Builder
+	for n, r := range list {
+		prefix := fmt.Sprintf("#%d (old ID %d): ", n+1, r.ID)
+
+		r.UserID = ctx.userID
+		if r.ChannelID == "" {
+			r.ChannelID = ctx.channelID
 		}
+		recurring := r.CronExpr.Valid && r.CronExpr.String != ""
 
-		if r.CronExpr.Valid && r.CronExpr.String != "" {
-			list = append(list, r)
-		} else if dueTimeStr.Valid {
-			t, err := time.Parse(time.RFC3339, dueTimeStr.String)
-			if err == nil && t.After(now) {
-				r.DueTime = t
-				list = append(list, r)
+		switch {
+		case strings.TrimSpace(r.Message) == "":
+			report.WriteString(prefix + "skipped, message is empty\n")
+			continue
+		case recurring:
+			if r.Timezone != "" {
+				if _, err := time.LoadLocation(r.Timezone); err != nil {
+					report.WriteString(prefix + "skipped, unknown timezone\n")
+					continue
+				}
+			}
+			if _, err := parseCron(r.CronExpr.String, reminderLocation(r)); err != nil {
+				report.WriteString(prefix + "skipped, invalid cron expression\n")
+				continue
+			}
+			if r.HolidayCalendar != "" {
+				if _, ok := holidayCalendars[r.HolidayCalendar]; !ok {
+					report.WriteString(prefix + "skipped, unknown holiday calendar\n")
+					continue
+				}
+			}
+			if !r.Until.IsZero() && !r.Until.After(now) {
+				report.WriteString(prefix + "skipped, end time is in the past\n")
+				continue
+			}
+		case r.DueTime.IsZero():
+			report.WriteString(prefix + "skipped, no due time or cron expression\n")
+			continue
+		case !r.DueTime.After(now):
+			report.WriteString(prefix + "skipped, due time is in the past\n")
+			continue
+		}
+
+		if !canUseChannel(ctx.s, ctx.userID, r.ChannelID) {
+			report.WriteString(prefix + "skipped, channel is not accessible\n")
+			continue
+		}
+
+		mentions, err := validateMentions(ctx.s, ctx.guildID, r.Mentions)
+		if err != nil {
+			report.WriteString(prefix + "skipped, " + err.Error() + "\n")
+			continue
+		}
+		r.Mentions = mentions
+
+		id, err := saveReminder(r)
+		if err != nil {
+			report.WriteString(prefix + "failed, " + err.Error() + "\n")
+			continue
+		}
+
+		if recurring {
+			if r.Paused {
+				if err := pauseRecurringReminder(id, r.PausedUntil); err != nil {
+					log.Printf("Error pausing imported reminder %d: %v", id, err)
+				}
 			}
+			scheduleRecurringReminder(ctx.s, id, r)
+		} else {
+			scheduleReminder(id, r)
 		}
+
+		imported++
+		report.WriteString(fmt.Sprintf("%simported as ID %d\n", prefix, id))
 	}
 
-	return json.MarshalIndent(list, "", "  ")
+	for _, chunk := range splitMessage(fmt.Sprintf("Imported %d of %d reminders:\n%s", imported, len(list), report.String()), maxMessageLength) {
+		ctx.reply(chunk)
+	}
+}
+
+func downloadAttachment(url string) ([]byte, error) {
+	resp, err := attachmentClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImportSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImportSize {
+		return nil, fmt.Errorf("file is larger than %d bytes", maxImportSize)
+	}
+	return data, nil
+}
+
+// canUseChannel reports whether the bot can see channelID and userID is
+// allowed to read and post in it.
+func canUseChannel(s *discordgo.Session, userID, channelID string) bool {
+	ch, err := s.Channel(channelID)
+	if err != nil {
+		return false
+	}
+
+	if ch.Type == discordgo.ChannelTypeDM || ch.Type == discordgo.ChannelTypeGroupDM {
+		for _, u := range ch.Recipients {
+			if u.ID == userID {
+				return true
+			}
+		}
+		return false
+	}
+
+	perms, err := s.UserChannelPermissions(userID, channelID)
+	if err != nil {
+		return false
+	}
+	return perms&discordgo.PermissionViewChannel != 0 && perms&discordgo.PermissionSendMessages != 0
 }
 
 func handleStopRecurringInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, id int) {
-	ok, err := isReminderOwner(id, i.Member.User.ID)
+	ok, err := isReminderOwner(id, interactionUserID(i))
 	if err != nil {
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
@@ -801,7 +2386,7 @@ func handleStopRecurringInteraction(s *discordgo.Session, i *discordgo.Interacti
 }
 
 func handlePauseRecurringInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, id int) {
-	ok, err := isReminderOwner(id, i.Member.User.ID)
+	ok, err := isReminderOwner(id, interactionUserID(i))
 	if err != nil {
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
@@ -823,7 +2408,16 @@ func handlePauseRecurringInteraction(s *discordgo.Session, i *discordgo.Interact
 		return
 	}
 
-	pauseRecurringReminder(id)
+	if err := pauseRecurringReminder(id, time.Time{}); err != nil {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Failed to pause reminder",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
 
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
@@ -835,10 +2429,38 @@ func handlePauseRecurringInteraction(s *discordgo.Session, i *discordgo.Interact
 }
 
 func interactionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if i.Type != discordgo.InteractionMessageComponent {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		handleApplicationCommand(s, i)
+	case discordgo.InteractionMessageComponent:
+		handleComponentInteraction(s, i)
+	case discordgo.InteractionModalSubmit:
+		handleModalSubmit(s, i)
+	}
+}
+
+func handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	parts := strings.Split(i.ModalSubmitData().CustomID, ":")
+	if len(parts) != 2 {
+		return
+	}
+	// Message IDs are snowflakes rather than reminder IDs.
+	if parts[0] == customIDRemindMessage {
+		handleRemindMessageSubmit(s, i, parts[1])
+		return
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
 		return
 	}
 
+	switch parts[0] {
+	case customIDSnoozeCustom:
+		handleSnoozeModalSubmit(s, i, id)
+	}
+}
+
+func handleComponentInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	data := i.MessageComponentData()
 
 	parts := strings.Split(data.CustomID, ":")
@@ -857,59 +2479,135 @@ func interactionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		handlePauseRecurringInteraction(s, i, id)
 	case customIDSnoozeReminder:
 		handleSnoozeInteraction(s, i, id, data.Values[0])
+	case customIDNagDone:
+		handleNagDoneInteraction(s, i, id)
+	case customIDSkipRecurring:
+		runSkip(newInteractionContext(s, i), id, 1)
 	}
 }
 
 func handleSnoozeInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, id int, value string) {
-	if i.Message != nil && time.Since(i.Message.Timestamp) > 5*time.Minute {
+	if value == snoozeCustomValue {
+		if !checkSnoozeOwner(s, i, id) {
+			return
+		}
+		if _, ok := snoozableReminder(id); !ok {
+			respondEphemeral(s, i, "Snooze expired. Please create a new reminder")
+			return
+		}
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Type: discordgo.InteractionResponseModal,
 			Data: &discordgo.InteractionResponseData{
-				Content: "Snooze expired. Please create a new reminder",
-				Flags:   discordgo.MessageFlagsEphemeral,
+				CustomID: fmt.Sprintf("%s:%d", customIDSnoozeCustom, id),
+				Title:    "Snooze reminder",
+				Components: []discordgo.MessageComponent{
+					discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    snoozeInputID,
+							Label:       "Snooze until",
+							Style:       discordgo.TextInputShort,
+							Placeholder: "2h, tomorrow 9am, friday 14:00",
+							Required:    true,
+							MaxLength:   100,
+						},
+					}},
+				},
 			},
 		})
 		return
 	}
 
-	rInterface, ok := snoozedEntries.Load(id)
+	snoozeReminder(s, i, id, value)
+}
+
+func handleSnoozeModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate, id int) {
+	value := ""
+	for _, c := range i.ModalSubmitData().Components {
+		row, ok := c.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, rc := range row.Components {
+			if input, ok := rc.(*discordgo.TextInput); ok && input.CustomID == snoozeInputID {
+				value = input.Value
+			}
+		}
+	}
+
+	snoozeReminder(s, i, id, value)
+}
+
+// snoozableReminder returns a reminder that has fired and is either still
+// nagging or within its snooze window.
+func snoozableReminder(id int) (Reminder, bool) {
+	r, err := store.GetReminder(id)
+	if err != nil {
+		return Reminder{}, false
+	}
+	if r.DeliveredAt.IsZero() {
+		return r, r.NagInterval > 0 && r.NagCount > 0
+	}
+	return r, time.Since(r.DeliveredAt) <= snoozeWindow
+}
+
+// checkSnoozeOwner reports whether the interacting user owns reminder id.
+// Everyone pinged by a delivery sees its snooze menu, so others are turned
+// away with an ephemeral reply.
+func checkSnoozeOwner(s *discordgo.Session, i *discordgo.InteractionCreate, id int) bool {
+	ok, err := isReminderOwner(id, interactionUserID(i))
+	if err != nil {
+		respondEphemeral(s, i, "Snooze expired. Please create a new reminder")
+		return false
+	}
 	if !ok {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Snooze expired. Please create a new reminder",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
-		return
+		respondEphemeral(s, i, "Only the owner can snooze this reminder")
+		return false
 	}
+	return true
+}
 
-	r := rInterface.(Reminder)
-	dur, _ := time.ParseDuration(value)
-	r.DueTime = time.Now().Add(dur)
+// snoozeReminder reschedules a delivered reminder under its original ID. The
+// value accepts anything !remind does, e.g. "10m" or "tomorrow 9am".
+func snoozeReminder(s *discordgo.Session, i *discordgo.InteractionCreate, id int, value string) {
+	if !checkSnoozeOwner(s, i, id) {
+		return
+	}
+	r, ok := snoozableReminder(id)
+	if !ok {
+		respondEphemeral(s, i, "Snooze expired. Please create a new reminder")
+		return
+	}
 
-	newid, err := saveReminder(r)
-	if err == nil {
-		scheduleReminder(s, newid, r)
+	dueTime, err := parseReminderTime(strings.TrimSpace(value), userLocation(interactionUserID(i)))
+	if err != nil {
+		respondEphemeral(s, i, "Invalid time format. Use a duration (e.g., 5m, 2h, 1d), a specific time (e.g., 2023-05-20T15:04:05) or a phrase (e.g., tomorrow at 9am, next friday 14:00).")
+		return
+	}
+	if dueTime.Before(time.Now()) {
+		respondEphemeral(s, i, "Error: Reminder time must be in the future.")
+		return
 	}
 
-	snoozedEntries.Delete(id)
+	r.DueTime = dueTime
+	r.DeliveredAt = time.Time{}
+	r.DeferredUntil = time.Time{}
 
+	err = store.UpdateReminder(r)
 	if err != nil {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Error scheduling snoozed reminder: " + err.Error(),
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
-	} else {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: fmt.Sprintf("Snoozed for %s", value),
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
+		respondEphemeral(s, i, "Error scheduling snoozed reminder: "+err.Error())
+		return
 	}
+	scheduleReminder(id, r)
+
+	respondEphemeral(s, i, fmt.Sprintf("Snoozed until <t:%d:F>, <t:%d:R>", dueTime.Unix(), dueTime.Unix()))
+}
+
+func respondEphemeral(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
 }
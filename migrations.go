@@ -0,0 +1,323 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	dialectSQLite   = "sqlite"
+	dialectPostgres = "postgres"
+)
+
+// migration is one numbered schema change. Versions must be contiguous and
+// increasing; a migration is never edited once released, only followed by a
+// new one.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx, dialect string) error
+}
+
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create reminders and user_settings",
+		up: func(tx *sql.Tx, dialect string) error {
+			// Tables may already exist in databases created before
+			// migrations were versioned, so this one is idempotent.
+			reminders := `CREATE TABLE IF NOT EXISTS reminders (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        channel_id TEXT,
+        user_id TEXT,
+        message TEXT,
+        due_time DATETIME,
+        cron_expr TEXT,
+        paused INTEGER NOT NULL DEFAULT 0
+    )`
+			pausedType := "INTEGER NOT NULL DEFAULT 0"
+			if dialect == dialectPostgres {
+				reminders = `CREATE TABLE IF NOT EXISTS reminders (
+        id SERIAL PRIMARY KEY,
+        channel_id TEXT,
+        user_id TEXT,
+        message TEXT,
+        due_time TEXT,
+        cron_expr TEXT,
+        paused BOOLEAN NOT NULL DEFAULT FALSE
+    )`
+				pausedType = "BOOLEAN NOT NULL DEFAULT FALSE"
+			}
+
+			if _, err := tx.Exec(reminders); err != nil {
+				return err
+			}
+			if err := addColumn(tx, dialect, "reminders", "paused", pausedType); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS user_settings (
+        user_id TEXT PRIMARY KEY,
+        timezone TEXT
+    )`)
+			return err
+		},
+	},
+	{
+		version: 2,
+		name:    "store due times in UTC and index them",
+		up: func(tx *sql.Tx, dialect string) error {
+			rows, err := tx.Query("SELECT id, due_time FROM reminders WHERE due_time IS NOT NULL")
+			if err != nil {
+				return err
+			}
+
+			dueTimes := make(map[int]string)
+			for rows.Next() {
+				var id int
+				var dueTime string
+				if err := rows.Scan(&id, &dueTime); err != nil {
+					rows.Close()
+					return err
+				}
+				t, err := time.Parse(time.RFC3339, dueTime)
+				if err != nil {
+					log.Printf("Skipping reminder %d with malformed due time %q", id, dueTime)
+					continue
+				}
+				dueTimes[id] = formatDueTime(t)
+			}
+			if err := rows.Err(); err != nil {
+				rows.Close()
+				return err
+			}
+			rows.Close()
+
+			update := "UPDATE reminders SET due_time = ? WHERE id = ?"
+			if dialect == dialectPostgres {
+				update = rebindDollar(update)
+			}
+			for id, dueTime := range dueTimes {
+				if _, err := tx.Exec(update, dueTime, id); err != nil {
+					return err
+				}
+			}
+
+			_, err = tx.Exec("CREATE INDEX IF NOT EXISTS idx_reminders_due_time ON reminders (due_time)")
+			return err
+		},
+	},
+	{
+		version: 3,
+		name:    "keep delivered reminders for snoozing",
+		up: func(tx *sql.Tx, dialect string) error {
+			return addColumn(tx, dialect, "reminders", "delivered_at", "TEXT")
+		},
+	},
+	{
+		version: 4,
+		name:    "add nag interval and count",
+		up: func(tx *sql.Tx, dialect string) error {
+			if err := addColumn(tx, dialect, "reminders", "nag_interval", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+				return err
+			}
+			return addColumn(tx, dialect, "reminders", "nag_count", "INTEGER NOT NULL DEFAULT 0")
+		},
+	},
+	{
+		version: 5,
+		name:    "add mentions to ping on delivery",
+		up: func(tx *sql.Tx, dialect string) error {
+			return addColumn(tx, dialect, "reminders", "mentions", "TEXT")
+		},
+	},
+	{
+		version: 6,
+		name:    "add end time and occurrence limit to recurring reminders",
+		up: func(tx *sql.Tx, dialect string) error {
+			if err := addColumn(tx, dialect, "reminders", "ends_at", "TEXT"); err != nil {
+				return err
+			}
+			if err := addColumn(tx, dialect, "reminders", "max_fires", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+				return err
+			}
+			return addColumn(tx, dialect, "reminders", "fire_count", "INTEGER NOT NULL DEFAULT 0")
+		},
+	},
+	{
+		version: 7,
+		name:    "add per-reminder timezone",
+		up: func(tx *sql.Tx, dialect string) error {
+			return addColumn(tx, dialect, "reminders", "timezone", "TEXT")
+		},
+	},
+	{
+		version: 8,
+		name:    "add skipped occurrences to recurring reminders",
+		up: func(tx *sql.Tx, dialect string) error {
+			return addColumn(tx, dialect, "reminders", "skip_count", "INTEGER NOT NULL DEFAULT 0")
+		},
+	},
+	{
+		version: 9,
+		name:    "add end time to pauses",
+		up: func(tx *sql.Tx, dialect string) error {
+			return addColumn(tx, dialect, "reminders", "paused_until", "TEXT")
+		},
+	},
+	{
+		version: 10,
+		name:    "add holiday calendar to recurring reminders",
+		up: func(tx *sql.Tx, dialect string) error {
+			return addColumn(tx, dialect, "reminders", "holiday_calendar", "TEXT")
+		},
+	},
+	{
+		version: 11,
+		name:    "add quiet hours and urgent reminders",
+		up: func(tx *sql.Tx, dialect string) error {
+			urgentType := "INTEGER NOT NULL DEFAULT 0"
+			if dialect == dialectPostgres {
+				urgentType = "BOOLEAN NOT NULL DEFAULT FALSE"
+			}
+			if err := addColumn(tx, dialect, "reminders", "urgent", urgentType); err != nil {
+				return err
+			}
+			if err := addColumn(tx, dialect, "reminders", "queued_count", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+				return err
+			}
+			return addColumn(tx, dialect, "user_settings", "quiet_hours", "TEXT")
+		},
+	},
+	{
+		version: 12,
+		name:    "add advance warnings",
+		up: func(tx *sql.Tx, dialect string) error {
+			if err := addColumn(tx, dialect, "reminders", "warnings", "TEXT"); err != nil {
+				return err
+			}
+			return addColumn(tx, dialect, "reminders", "warned_at", "TEXT")
+		},
+	},
+	{
+		version: 13,
+		name:    "add delivery by DM",
+		up: func(tx *sql.Tx, dialect string) error {
+			boolType := "INTEGER NOT NULL DEFAULT 0"
+			if dialect == dialectPostgres {
+				boolType = "BOOLEAN NOT NULL DEFAULT FALSE"
+			}
+			if err := addColumn(tx, dialect, "reminders", "dm", boolType); err != nil {
+				return err
+			}
+			return addColumn(tx, dialect, "user_settings", "dm_default", boolType)
+		},
+	},
+	{
+		version: 14,
+		name:    "add source message",
+		up: func(tx *sql.Tx, dialect string) error {
+			for _, column := range []string{"source_guild_id", "source_channel_id", "source_message_id", "quote"} {
+				if err := addColumn(tx, dialect, "reminders", column, "TEXT"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version: 15,
+		name:    "hold one-shot reminders for quiet hours without moving their due time",
+		up: func(tx *sql.Tx, dialect string) error {
+			if err := addColumn(tx, dialect, "reminders", "deferred_until", "TEXT"); err != nil {
+				return err
+			}
+			// Reminders already held had their due time moved, so they
+			// carry on as ordinary ones.
+			_, err := tx.Exec("UPDATE reminders SET queued_count = 0 WHERE cron_expr IS NULL OR cron_expr = ''")
+			return err
+		},
+	},
+}
+
+// migrate brings the schema up to the latest migration in one transaction.
+// It refuses to run against a database written by a newer binary.
+func (st *sqlStore) migrate() error {
+	_, err := st.db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+        version INTEGER PRIMARY KEY,
+        applied_at TEXT NOT NULL
+    )`)
+	if err != nil {
+		return fmt.Errorf("creating schema_version table: %w", err)
+	}
+
+	var current int
+	err = st.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&current)
+	if err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+
+	latest := migrations[len(migrations)-1].version
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than this binary supports (%d)", current, latest)
+	}
+	if current == latest {
+		return nil
+	}
+
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := m.up(tx, st.dialect); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+		_, err := tx.Exec(st.rebind("INSERT INTO schema_version (version, applied_at) VALUES (?, CURRENT_TIMESTAMP)"), m.version)
+		if err != nil {
+			return fmt.Errorf("recording migration %d: %w", m.version, err)
+		}
+		log.Printf("Applied migration %d: %s", m.version, m.name)
+	}
+
+	return tx.Commit()
+}
+
+// addColumn adds a column unless it already exists.
+func addColumn(tx *sql.Tx, dialect, table, column, definition string) error {
+	if dialect == dialectPostgres {
+		_, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", table, column, definition))
+		return err
+	}
+
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// naturalDefaultHour is used when a phrase names a day but not a time.
+	naturalDefaultHour = 9
+	// naturalEndOfDayHour is what "end of day" and "end of week" resolve to.
+	naturalEndOfDayHour = 17
+	// maxNaturalTokens bounds how many leading words of !remind are tried as
+	// a time phrase.
+	maxNaturalTokens = 8
+)
+
+var (
+	naturalWeekdays = map[string]time.Weekday{
+		"sunday": time.Sunday, "sun": time.Sunday,
+		"monday": time.Monday, "mon": time.Monday,
+		"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+		"wednesday": time.Wednesday, "wed": time.Wednesday,
+		"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+		"friday": time.Friday, "fri": time.Friday,
+		"saturday": time.Saturday, "sat": time.Saturday,
+	}
+	naturalMonths = map[string]time.Month{
+		"january": time.January, "jan": time.January,
+		"february": time.February, "feb": time.February,
+		"march": time.March, "mar": time.March,
+		"april": time.April, "apr": time.April,
+		"may":  time.May,
+		"june": time.June, "jun": time.June,
+		"july": time.July, "jul": time.July,
+		"august": time.August, "aug": time.August,
+		"september": time.September, "sep": time.September, "sept": time.September,
+		"october": time.October, "oct": time.October,
+		"november": time.November, "nov": time.November,
+		"december": time.December, "dec": time.December,
+	}
+	naturalDayParts = map[string]int{
+		"noon":      12,
+		"midnight":  0,
+		"morning":   9,
+		"afternoon": 15,
+		"evening":   18,
+	}
+
+	clockRe   = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(?::(\d{2}))?(am|pm)?$`)
+	ordinalRe = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?$`)
+	yearRe    = regexp.MustCompile(`^\d{4}$`)
+)
+
+// naturalTime accumulates the pieces of a phrase such as "next friday at 3pm"
+// before they are resolved against now.
+type naturalTime struct {
+	now time.Time
+
+	offset    time.Duration
+	hasOffset bool
+
+	date    time.Time
+	hasDate bool
+
+	hour, minute, second int
+	hasClock             bool
+	defaultHour          int
+
+	// roll moves the result forward when it has already passed, e.g. a day
+	// for "3pm" or a week for "monday". Nil means the phrase was exact.
+	roll func(time.Time) time.Time
+}
+
+// parseNaturalTime resolves phrases like "tomorrow at 9am", "next friday
+// 14:00", "in 2 hours 30 minutes", "end of day", "monday" or "May 20 3pm"
+// relative to now, in now's location.
+func parseNaturalTime(input string, now time.Time) (time.Time, error) {
+	tokens := strings.Fields(strings.ToLower(strings.ReplaceAll(input, ",", " ")))
+	if len(tokens) == 0 {
+		return time.Time{}, fmt.Errorf("empty time")
+	}
+
+	p := &naturalTime{now: now, defaultHour: naturalDefaultHour}
+	for i := 0; i < len(tokens); {
+		n := p.consume(tokens[i:])
+		if n == 0 {
+			return time.Time{}, fmt.Errorf("unable to parse time: unexpected %q", tokens[i])
+		}
+		i += n
+	}
+
+	return p.resolve()
+}
+
+// parseNaturalDuration parses a span the way "in ..." does, e.g. "1 week 3
+// days", "2h30m" or "an hour and 5 mins".
+func parseNaturalDuration(input string) (time.Duration, error) {
+	tokens := strings.Fields(strings.ToLower(strings.ReplaceAll(input, ",", " ")))
+	if len(tokens) == 0 {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	p := &naturalTime{}
+	if n := p.durations(tokens); n < len(tokens) {
+		return 0, fmt.Errorf("unable to parse duration: unexpected %q", tokens[n])
+	}
+	return p.offset, nil
+}
+
+// consume matches one component at the start of tokens and returns how many
+// tokens it used, or 0 if nothing matched.
+func (p *naturalTime) consume(tokens []string) int {
+	switch tokens[0] {
+	case "in":
+		if len(tokens) > 1 {
+			if n := p.durations(tokens[1:]); n > 0 {
+				return n + 1
+			}
+		}
+		return 0
+	case "on":
+		if len(tokens) > 1 {
+			if n := p.day(tokens[1:]); n > 0 {
+				return n + 1
+			}
+		}
+		return 0
+	case "at":
+		if len(tokens) > 1 {
+			if n := p.clock(tokens[1:], true); n > 0 {
+				return n + 1
+			}
+		}
+		return 0
+	}
+
+	if n := p.durations(tokens); n > 0 {
+		return n
+	}
+	if n := p.day(tokens); n > 0 {
+		return n
+	}
+	return p.clock(tokens, false)
+}
+
+// durations matches "2 hours 30 minutes", "1h30m", "an hour and 5 mins".
+func (p *naturalTime) durations(tokens []string) int {
+	var total time.Duration
+	i, count := 0, 0
+	for i < len(tokens) {
+		if count > 0 && tokens[i] == "and" && i+1 < len(tokens) {
+			d, n := durationTerm(tokens[i+1:])
+			if n == 0 {
+				break
+			}
+			total += d
+			i += n + 1
+			count++
+			continue
+		}
+		d, n := durationTerm(tokens[i:])
+		if n == 0 {
+			break
+		}
+		total += d
+		i += n
+		count++
+	}
+	if count == 0 || p.hasOffset {
+		return 0
+	}
+	p.offset = total
+	p.hasOffset = true
+	return i
+}
+
+func durationTerm(tokens []string) (time.Duration, int) {
+	if d, err := parseDuration(tokens[0]); err == nil && d > 0 {
+		return d, 1
+	}
+	if len(tokens) < 2 {
+		return 0, 0
+	}
+	value := tokens[0]
+	if value == "a" || value == "an" {
+		value = "1"
+	}
+	if _, err := strconv.Atoi(value); err != nil {
+		return 0, 0
+	}
+	if d, err := parseDuration(value + tokens[1]); err == nil {
+		return d, 2
+	}
+	return 0, 0
+}
+
+// day matches a calendar day: today, tomorrow, tonight, weekdays, "next
+// week", "end of day/week" and month-day dates with an optional year.
+func (p *naturalTime) day(tokens []string) int {
+	if p.hasDate {
+		return 0
+	}
+
+	today := time.Date(p.now.Year(), p.now.Month(), p.now.Day(), 0, 0, 0, 0, p.now.Location())
+
+	switch tokens[0] {
+	case "today":
+		p.setDate(today, nil)
+		return 1
+	case "tomorrow":
+		p.setDate(today.AddDate(0, 0, 1), nil)
+		return 1
+	case "tonight":
+		p.setDate(today, nil)
+		p.defaultHour = 20
+		return 1
+	case "eod":
+		return p.endOfDay(today, 1)
+	case "eow":
+		return p.endOfWeek(today, 1)
+	case "end":
+		if len(tokens) >= 3 && tokens[1] == "of" {
+			switch tokens[2] {
+			case "day":
+				return p.endOfDay(today, 3)
+			case "week":
+				return p.endOfWeek(today, 3)
+			}
+		}
+		return 0
+	case "next":
+		if len(tokens) < 2 {
+			return 0
+		}
+		if tokens[1] == "week" {
+			ahead := (int(time.Monday) - int(today.Weekday()) + 7) % 7
+			if ahead == 0 {
+				ahead = 7
+			}
+			p.setDate(today.AddDate(0, 0, ahead), nil)
+			return 2
+		}
+		if wd, ok := naturalWeekdays[tokens[1]]; ok {
+			ahead := (int(wd) - int(today.Weekday()) + 7) % 7
+			if ahead == 0 {
+				ahead = 7
+			}
+			p.setDate(today.AddDate(0, 0, ahead), nil)
+			return 2
+		}
+		return 0
+	}
+
+	if wd, ok := naturalWeekdays[tokens[0]]; ok {
+		ahead := (int(wd) - int(today.Weekday()) + 7) % 7
+		p.setDate(today.AddDate(0, 0, ahead), func(t time.Time) time.Time { return t.AddDate(0, 0, 7) })
+		return 1
+	}
+
+	// "May 20", "May 20th 2025"
+	if month, ok := naturalMonths[tokens[0]]; ok && len(tokens) >= 2 {
+		if n := p.monthDay(month, tokens[1], tokens[2:]); n > 0 {
+			return n + 1
+		}
+		return 0
+	}
+
+	// "20 May", "20th May 2025"
+	if len(tokens) >= 2 {
+		if month, ok := naturalMonths[tokens[1]]; ok {
+			if n := p.monthDay(month, tokens[0], tokens[2:]); n > 0 {
+				return n + 1
+			}
+		}
+	}
+
+	return 0
+}
+
+// monthDay sets a date from a day-of-month token and an optional year in
+// rest, returning the tokens used excluding the month name.
+func (p *naturalTime) monthDay(month time.Month, dayToken string, rest []string) int {
+	m := ordinalRe.FindStringSubmatch(dayToken)
+	if m == nil {
+		return 0
+	}
+	day, _ := strconv.Atoi(m[1])
+
+	year := p.now.Year()
+	used := 1
+	explicitYear := false
+	if len(rest) > 0 && yearRe.MatchString(rest[0]) {
+		year, _ = strconv.Atoi(rest[0])
+		used++
+		explicitYear = true
+	}
+
+	date := time.Date(year, month, day, 0, 0, 0, 0, p.now.Location())
+	if date.Month() != month || date.Day() != day {
+		return 0
+	}
+
+	if explicitYear {
+		p.setDate(date, nil)
+	} else {
+		p.setDate(date, func(t time.Time) time.Time { return t.AddDate(1, 0, 0) })
+	}
+	return used
+}
+
+func (p *naturalTime) endOfDay(today time.Time, used int) int {
+	if p.hasClock {
+		return 0
+	}
+	p.setDate(today, func(t time.Time) time.Time { return t.AddDate(0, 0, 1) })
+	p.setClock(naturalEndOfDayHour, 0, 0)
+	return used
+}
+
+func (p *naturalTime) endOfWeek(today time.Time, used int) int {
+	if p.hasClock {
+		return 0
+	}
+	ahead := (int(time.Friday) - int(today.Weekday()) + 7) % 7
+	p.setDate(today.AddDate(0, 0, ahead), func(t time.Time) time.Time { return t.AddDate(0, 0, 7) })
+	p.setClock(naturalEndOfDayHour, 0, 0)
+	return used
+}
+
+// clock matches a time of day: "9am", "9 pm", "14:00", "noon", "morning".
+// A bare hour such as "9" is only accepted after "at".
+func (p *naturalTime) clock(tokens []string, afterAt bool) int {
+	if p.hasClock {
+		return 0
+	}
+
+	if hour, ok := naturalDayParts[tokens[0]]; ok {
+		p.setClock(hour, 0, 0)
+		return 1
+	}
+
+	m := clockRe.FindStringSubmatch(tokens[0])
+	if m == nil {
+		return 0
+	}
+
+	used := 1
+	meridiem := m[4]
+	if meridiem == "" && len(tokens) > 1 && (tokens[1] == "am" || tokens[1] == "pm") {
+		meridiem = tokens[1]
+		used++
+	}
+	if meridiem == "" && m[2] == "" && !afterAt {
+		return 0
+	}
+
+	hour, _ := strconv.Atoi(m[1])
+	minute, _ := strconv.Atoi(m[2])
+	second, _ := strconv.Atoi(m[3])
+	if minute > 59 || second > 59 {
+		return 0
+	}
+
+	switch meridiem {
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return 0
+		}
+		if meridiem == "pm" && hour < 12 {
+			hour += 12
+		} else if meridiem == "am" && hour == 12 {
+			hour = 0
+		}
+	default:
+		if hour > 23 {
+			return 0
+		}
+	}
+
+	p.setClock(hour, minute, second)
+	return used
+}
+
+func (p *naturalTime) setDate(date time.Time, roll func(time.Time) time.Time) {
+	p.date = date
+	p.hasDate = true
+	p.roll = roll
+}
+
+func (p *naturalTime) setClock(hour, minute, second int) {
+	p.hour, p.minute, p.second = hour, minute, second
+	p.hasClock = true
+}
+
+func (p *naturalTime) resolve() (time.Time, error) {
+	if p.hasOffset {
+		if p.hasDate || p.hasClock {
+			return time.Time{}, fmt.Errorf("cannot combine a duration with a date or time")
+		}
+		return p.now.Add(p.offset), nil
+	}
+
+	date := p.date
+	roll := p.roll
+	if !p.hasDate {
+		date = time.Date(p.now.Year(), p.now.Month(), p.now.Day(), 0, 0, 0, 0, p.now.Location())
+		roll = func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	}
+
+	hour, minute, second := p.defaultHour, 0, 0
+	if p.hasClock {
+		hour, minute, second = p.hour, p.minute, p.second
+	}
+
+	t := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, second, 0, date.Location())
+	if !t.After(p.now) && roll != nil {
+		t = roll(t)
+	}
+	return t, nil
+}
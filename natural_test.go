@@ -0,0 +1,126 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseNaturalTime(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A Monday morning.
+	now := time.Date(2025, time.May, 26, 10, 30, 0, 0, loc)
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, loc)
+	}
+
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"tomorrow at 9am", at(2025, time.May, 27, 9, 0)},
+		{"Tomorrow 9AM", at(2025, time.May, 27, 9, 0)},
+		{"tomorrow", at(2025, time.May, 27, 9, 0)},
+		{"next friday 14:00", at(2025, time.May, 30, 14, 0)},
+		{"friday at 2pm", at(2025, time.May, 30, 14, 0)},
+		{"in 2 hours 30 minutes", at(2025, time.May, 26, 13, 0)},
+		{"in 90m", at(2025, time.May, 26, 12, 0)},
+		{"end of day", at(2025, time.May, 26, 17, 0)},
+		{"end of week", at(2025, time.May, 30, 17, 0)},
+		// 9am today has passed, so a bare weekday means next week...
+		{"monday", at(2025, time.June, 2, 9, 0)},
+		// ...but a later time on the same weekday is still today.
+		{"monday 3pm", at(2025, time.May, 26, 15, 0)},
+		{"next monday", at(2025, time.June, 2, 9, 0)},
+		{"3pm", at(2025, time.May, 26, 15, 0)},
+		{"9am", at(2025, time.May, 27, 9, 0)},
+		{"May 27", at(2025, time.May, 27, 9, 0)},
+		// May 20 has passed this year.
+		{"May 20 3pm", at(2026, time.May, 20, 15, 0)},
+		{"20 may 2027 noon", at(2027, time.May, 20, 12, 0)},
+		{"tonight", at(2025, time.May, 26, 20, 0)},
+	}
+	for _, tt := range tests {
+		got, err := parseNaturalTime(tt.input, now)
+		if err != nil {
+			t.Errorf("parseNaturalTime(%q): %v", tt.input, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseNaturalTime(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseNaturalTimeRejects(t *testing.T) {
+	now := time.Date(2025, time.May, 26, 10, 30, 0, 0, time.UTC)
+
+	for _, input := range []string{
+		"",
+		"banana",
+		"at",
+		"in",
+		"tomorrow at",
+		"tomorrow at 25:00",
+		"13pm",
+		"feb 30",
+		"3pm 4pm",
+		"tomorrow tomorrow",
+		"in -5 minutes",
+	} {
+		if got, err := parseNaturalTime(input, now); err == nil {
+			t.Errorf("parseNaturalTime(%q) = %v, want error", input, got)
+		}
+	}
+}
+
+func TestParseNaturalDuration(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{"2h", 2 * time.Hour},
+		{"3d", 3 * day},
+		{"1 week 3 days", 10 * day},
+		{"2 days 4 hours", 2*day + 4*time.Hour},
+		{"an hour and 5 mins", 65 * time.Minute},
+		{"1h30m", 90 * time.Minute},
+	}
+	for _, tt := range tests {
+		got, err := parseNaturalDuration(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("parseNaturalDuration(%q) = %v, %v, want %v", tt.input, got, err, tt.want)
+		}
+	}
+
+	for _, input := range []string{"", "banana", "2 days banana", "tomorrow", "and 2 days"} {
+		if got, err := parseNaturalDuration(input); err == nil {
+			t.Errorf("parseNaturalDuration(%q) = %v, want error", input, got)
+		}
+	}
+}
+
+func TestSplitReminderTime(t *testing.T) {
+	tests := []struct {
+		input   string
+		when    string
+		message string
+	}{
+		{"tomorrow at 9am standup", "tomorrow at 9am", "standup"},
+		{"in 2 hours 30 minutes call mom", "in 2 hours 30 minutes", "call mom"},
+		{"next friday 14:00 release cutoff", "next friday 14:00", "release cutoff"},
+		{"10m tea", "10m", "tea"},
+		{"monday", "", ""},
+		{"buy milk", "", ""},
+	}
+	for _, tt := range tests {
+		when, message := splitReminderTime(strings.Fields(tt.input), time.UTC)
+		if when != tt.when || message != tt.message {
+			t.Errorf("splitReminderTime(%q) = %q, %q, want %q, %q", tt.input, when, message, tt.when, tt.message)
+		}
+	}
+}
@@ -0,0 +1,607 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// maxRecurrenceTokens bounds how many leading words of !recurring are
+	// tried as a schedule phrase.
+	maxRecurrenceTokens = 12
+	// maxFilteredDays bounds how many days a filtered schedule looks ahead
+	// for a match, the same five years the cron library searches.
+	maxFilteredDays = 5 * 366
+)
+
+var (
+	cronWeekdays = [...]string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+	cronMonths   = [...]string{"", "JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+
+	recurrenceOrdinals = map[string]string{
+		"first": "1", "1st": "1",
+		"second": "2", "2nd": "2",
+		"third": "3", "3rd": "3",
+		"fourth": "4", "4th": "4",
+		"fifth": "5", "5th": "5",
+		"last": "L",
+	}
+
+	dayOrdinalRe  = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)$`)
+	nthWeekdayRe  = regexp.MustCompile(`^(?i)([a-z]{3}|[0-7])(?:#([1-5])|(L))$`)
+	monthSuffixes = [][]string{{"of", "the", "month"}, {"of", "every", "month"}, {"of", "each", "month"}, {"of", "month"}}
+)
+
+// isRecurrencePhrase reports whether s looks like a phrase for
+// compileRecurrence rather than a cron expression.
+func isRecurrencePhrase(s string) bool {
+	fields := strings.Fields(s)
+	return len(fields) > 0 && strings.EqualFold(fields[0], "every")
+}
+
+// compileRecurrence translates phrases such as "every weekday at 9:30",
+// "every 2 hours", "every first monday of the month at 10" or "every 15th at
+// noon" into a six-field cron expression. Days without a time fire at 9am.
+// The result is checked with parseCron, which hands it to parser.Parse after
+// rewriting the Quartz-style "MON#1" and "FRIL" day fields.
+func compileRecurrence(phrase string) (string, error) {
+	tokens := strings.Fields(strings.ToLower(strings.ReplaceAll(phrase, ",", " ")))
+	if len(tokens) < 2 || tokens[0] != "every" {
+		return "", fmt.Errorf("schedule must start with \"every\"")
+	}
+	tokens = tokens[1:]
+
+	if expr, ok, err := compileInterval(tokens); ok {
+		return expr, err
+	}
+
+	r := recurrence{dom: "*", month: "*", dow: "*"}
+	n := r.days(tokens)
+	if n == 0 {
+		return "", fmt.Errorf("unexpected %q", tokens[0])
+	}
+	tokens = tokens[n:]
+
+	hour, minute, second := naturalDefaultHour, 0, 0
+	if len(tokens) > 0 {
+		afterAt := tokens[0] == "at"
+		if afterAt {
+			tokens = tokens[1:]
+		}
+		if len(tokens) == 0 {
+			return "", fmt.Errorf("missing time after \"at\"")
+		}
+		p := &naturalTime{}
+		used := p.clock(tokens, afterAt)
+		if used == 0 {
+			return "", fmt.Errorf("unexpected %q", tokens[0])
+		}
+		if used < len(tokens) {
+			return "", fmt.Errorf("unexpected %q", tokens[used])
+		}
+		hour, minute, second = p.hour, p.minute, p.second
+	}
+
+	expr := fmt.Sprintf("%d %d %d %s %s %s", second, minute, hour, r.dom, r.month, r.dow)
+	if _, err := parseCron(expr, time.Local); err != nil {
+		return "", err
+	}
+	return expr, nil
+}
+
+// compileInterval handles "every 15 minutes", "every hour" and "every 30s".
+// ok is false when tokens are not an interval under a day, so the caller can
+// try them as days instead.
+func compileInterval(tokens []string) (expr string, ok bool, err error) {
+	d, n := durationTerm(tokens)
+	if n == 0 {
+		d, n = durationTerm(append([]string{"1"}, tokens...))
+		n--
+	}
+	if n <= 0 || n != len(tokens) || d >= 24*time.Hour {
+		return "", false, nil
+	}
+
+	switch {
+	case d%time.Hour == 0 && 24%int(d/time.Hour) == 0:
+		return "0 0 " + cronStep(int(d/time.Hour)) + " * * *", true, nil
+	case d < time.Hour && d%time.Minute == 0 && 60%int(d/time.Minute) == 0:
+		return "0 " + cronStep(int(d/time.Minute)) + " * * * *", true, nil
+	case d < time.Minute && d%time.Second == 0 && 60%int(d/time.Second) == 0:
+		return cronStep(int(d/time.Second)) + " * * * * *", true, nil
+	}
+	return "", true, fmt.Errorf("%s does not divide evenly into a day", strings.Join(tokens, " "))
+}
+
+func cronStep(n int) string {
+	if n == 1 {
+		return "*"
+	}
+	return fmt.Sprintf("*/%d", n)
+}
+
+// recurrence holds the day fields of a schedule phrase being compiled.
+type recurrence struct {
+	dom, month, dow string
+}
+
+// days matches which days a phrase fires on and returns how many tokens it
+// used, or 0 if nothing matched.
+func (r *recurrence) days(tokens []string) int {
+	switch tokens[0] {
+	case "day":
+		return 1
+	case "weekday", "weekdays":
+		r.dow = "MON-FRI"
+		return 1
+	case "weekend", "weekends":
+		r.dow = "SAT,SUN"
+		return 1
+	}
+
+	// "first monday of the month", "last friday", "last day of the month"
+	if nth, ok := recurrenceOrdinals[tokens[0]]; ok && len(tokens) >= 2 {
+		if wd, ok := naturalWeekdays[tokens[1]]; ok {
+			if nth == "L" {
+				r.dow = cronWeekdays[wd] + "L"
+			} else {
+				r.dow = cronWeekdays[wd] + "#" + nth
+			}
+			return 2 + monthSuffix(tokens[2:])
+		}
+		if nth == "L" && tokens[1] == "day" {
+			r.dom = "L"
+			return 2 + monthSuffix(tokens[2:])
+		}
+	}
+
+	// "15th", "15th of the month"
+	if m := dayOrdinalRe.FindStringSubmatch(tokens[0]); m != nil {
+		if day, _ := strconv.Atoi(m[1]); day >= 1 && day <= 31 {
+			r.dom = m[1]
+			return 1 + monthSuffix(tokens[1:])
+		}
+		return 0
+	}
+
+	// "may 20th", "20 may"
+	if len(tokens) >= 2 {
+		if month, ok := naturalMonths[tokens[0]]; ok {
+			if m := ordinalRe.FindStringSubmatch(tokens[1]); m != nil {
+				r.dom, r.month = m[1], cronMonths[month]
+				return 2
+			}
+		}
+		if month, ok := naturalMonths[tokens[1]]; ok {
+			if m := ordinalRe.FindStringSubmatch(tokens[0]); m != nil {
+				r.dom, r.month = m[1], cronMonths[month]
+				return 2
+			}
+		}
+	}
+
+	// "monday", "monday and thursday", "tue, thu"
+	var dows []string
+	used := 0
+	for used < len(tokens) {
+		if wd, ok := naturalWeekdays[tokens[used]]; ok {
+			dows = append(dows, cronWeekdays[wd])
+			used++
+			continue
+		}
+		if tokens[used] == "and" && len(dows) > 0 && used+1 < len(tokens) {
+			if _, ok := naturalWeekdays[tokens[used+1]]; ok {
+				used++
+				continue
+			}
+		}
+		break
+	}
+	if len(dows) > 0 {
+		r.dow = strings.Join(dows, ",")
+		return used
+	}
+
+	return 0
+}
+
+// monthSuffix returns how many tokens an optional "of the month" uses.
+func monthSuffix(tokens []string) int {
+	for _, suffix := range monthSuffixes {
+		if len(tokens) < len(suffix) {
+			continue
+		}
+		match := true
+		for i, word := range suffix {
+			if tokens[i] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			return len(suffix)
+		}
+	}
+	return 0
+}
+
+// leadingRecurrenceWords returns how many leading words form the longest
+// phrase compileRecurrence accepts, always leaving at least one word behind.
+func leadingRecurrenceWords(words []string) int {
+	n := len(words) - 1
+	if n > maxRecurrenceTokens {
+		n = maxRecurrenceTokens
+	}
+	for ; n > 1; n-- {
+		if _, err := compileRecurrence(strings.Join(words[:n], " ")); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// splitCronZone separates a leading CRON_TZ= or TZ= prefix from expr,
+// returning the expression without it and the zone name.
+func splitCronZone(expr string) (string, string) {
+	expr = strings.TrimSpace(expr)
+	for _, prefix := range []string{"CRON_TZ=", "TZ="} {
+		if strings.HasPrefix(expr, prefix) {
+			zone, rest, _ := strings.Cut(expr[len(prefix):], " ")
+			return strings.TrimSpace(rest), zone
+		}
+	}
+	return expr, ""
+}
+
+// splitDayFilter rewrites the Quartz-style day fields the cron library lacks
+// into ones it understands, returning a filter for the days they select: "L"
+// in day of month for the last day, and "MON#2" or "FRIL" in day of week for
+// the second Monday or last Friday.
+func splitDayFilter(expr string) (string, func(time.Time) bool, error) {
+	fields := strings.Fields(expr)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "@") {
+		return expr, nil, nil
+	}
+	body := fields
+	if strings.HasPrefix(fields[0], "CRON_TZ=") || strings.HasPrefix(fields[0], "TZ=") {
+		body = fields[1:]
+	}
+	if len(body) != 5 && len(body) != 6 {
+		return expr, nil, nil
+	}
+
+	dom := &body[len(body)-3]
+	dow := &body[len(body)-1]
+	var filter func(time.Time) bool
+
+	if strings.EqualFold(*dom, "L") {
+		*dom = "*"
+		filter = func(t time.Time) bool { return t.AddDate(0, 0, 1).Month() != t.Month() }
+	}
+
+	if m := nthWeekdayRe.FindStringSubmatch(*dow); m != nil {
+		if *dom != "*" {
+			return "", nil, fmt.Errorf("%s cannot be combined with a day of month", *dow)
+		}
+		*dow = m[1]
+		if m[3] != "" {
+			filter = func(t time.Time) bool { return t.AddDate(0, 0, 7).Month() != t.Month() }
+		} else {
+			nth, _ := strconv.Atoi(m[2])
+			filter = func(t time.Time) bool { return (t.Day()-1)/7+1 == nth }
+		}
+	}
+
+	if filter == nil {
+		return expr, nil, nil
+	}
+	return strings.Join(fields, " "), filter, nil
+}
+
+// filteredSchedule skips the times of schedule whose day, in loc, fails
+// match.
+type filteredSchedule struct {
+	schedule cron.Schedule
+	loc      *time.Location
+	match    func(time.Time) bool
+}
+
+// Next returns the first time of the schedule after t on a matching day.
+// Since match only looks at the day, a rejected time moves the search to the
+// next midnight rather than to the schedule's next time, which for a
+// per-second schedule would take a day's worth of steps.
+func (fs *filteredSchedule) Next(t time.Time) time.Time {
+	for i := 0; i < maxFilteredDays; i++ {
+		t = fs.schedule.Next(t)
+		if t.IsZero() {
+			return t
+		}
+		day := t.In(fs.loc)
+		if fs.match(day) {
+			return t
+		}
+		// The schedule's Next is strictly after its argument, at second
+		// granularity, so this resumes at midnight itself.
+		t = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, fs.loc).Add(-time.Second)
+	}
+	return time.Time{}
+}
+
+// previewCount is how many upcoming fire times previews list.
+const previewCount = 5
+
+var (
+	ordinalWords = [...]string{"", "first", "second", "third", "fourth", "fifth"}
+
+	cronDescriptors = map[string]string{
+		"@yearly":   "0 0 0 1 1 *",
+		"@annually": "0 0 0 1 1 *",
+		"@monthly":  "0 0 0 1 * *",
+		"@weekly":   "0 0 0 * * 0",
+		"@daily":    "0 0 0 * * *",
+		"@midnight": "0 0 0 * * *",
+		"@hourly":   "0 0 * * * *",
+	}
+)
+
+// describeCron renders a cron expression in English, e.g. "at 09:00 on
+// Monday through Friday". Expressions it cannot break down are returned
+// as-is.
+func describeCron(expr string) string {
+	fields := strings.Fields(expr)
+	zone := ""
+	if len(fields) > 0 && (strings.HasPrefix(fields[0], "CRON_TZ=") || strings.HasPrefix(fields[0], "TZ=")) {
+		zone = fields[0][strings.Index(fields[0], "=")+1:]
+		fields = fields[1:]
+	}
+
+	if len(fields) == 2 && fields[0] == "@every" {
+		return withZone("every "+fields[1], zone)
+	}
+	if len(fields) == 1 {
+		spec, ok := cronDescriptors[strings.ToLower(fields[0])]
+		if !ok {
+			return expr
+		}
+		fields = strings.Fields(spec)
+	}
+	if len(fields) == 5 {
+		fields = append([]string{"0"}, fields...)
+	}
+	if len(fields) != 6 {
+		return expr
+	}
+
+	desc := describeClock(fields[0], fields[1], fields[2])
+	if days := describeDays(fields[3], fields[5]); days != "" {
+		desc += " " + days
+	} else if isNumber(fields[2]) {
+		desc += " every day"
+	}
+	if fields[4] != "*" {
+		desc += " in " + describeList(fields[4], "month", monthName)
+	}
+	return withZone(desc, zone)
+}
+
+func withZone(desc, zone string) string {
+	if zone == "" {
+		return desc
+	}
+	return desc + " (" + zone + ")"
+}
+
+// describeClock covers the second, minute and hour fields.
+func describeClock(second, minute, hour string) string {
+	if isNumber(second) && isNumber(minute) && isNumber(hour) {
+		s, _ := strconv.Atoi(second)
+		m, _ := strconv.Atoi(minute)
+		h, _ := strconv.Atoi(hour)
+		if s == 0 {
+			return fmt.Sprintf("at %02d:%02d", h, m)
+		}
+		return fmt.Sprintf("at %02d:%02d:%02d", h, m, s)
+	}
+
+	switch {
+	case second == "0" && minute == "0":
+		return describeEvery(hour, "hour")
+	case second == "0" && hour == "*":
+		return describeEvery(minute, "minute")
+	case minute == "*" && hour == "*":
+		return describeEvery(second, "second")
+	}
+
+	var parts []string
+	if second != "0" {
+		parts = append(parts, describeEvery(second, "second"))
+	}
+	if minute != "*" || second != "0" {
+		parts = append(parts, describeEvery(minute, "minute"))
+	}
+	desc := strings.Join(parts, ", ")
+	switch {
+	case hour == "*":
+	case strings.HasPrefix(hour, "*/"):
+		desc += " past every " + hour[2:] + " hours"
+	case isNumber(hour):
+		desc += " past hour " + hour
+	default:
+		desc += " past hours " + describeList(hour, "hour", nil)
+	}
+	return desc
+}
+
+// describeEvery reads a clock field as "every hour", "every 2 hours", "at
+// minute 5" or "at minutes 0 and 30".
+func describeEvery(field, unit string) string {
+	if field == "*" || field == "*/1" {
+		return "every " + unit
+	}
+	if strings.HasPrefix(field, "*/") {
+		return "every " + field[2:] + " " + unit + "s"
+	}
+	if isNumber(field) {
+		return "at " + unit + " " + field
+	}
+	return "at " + unit + "s " + describeList(field, unit, nil)
+}
+
+// describeDays covers the day-of-month and day-of-week fields, which the
+// cron library ORs together when both are restricted.
+func describeDays(dom, dow string) string {
+	var days []string
+	if dom != "*" && dom != "?" {
+		if strings.EqualFold(dom, "L") {
+			days = append(days, "on the last day of the month")
+		} else {
+			days = append(days, "on day "+describeList(dom, "day", nil)+" of the month")
+		}
+	}
+	if dow != "*" && dow != "?" {
+		if m := nthWeekdayRe.FindStringSubmatch(dow); m != nil {
+			nth := "last"
+			if m[2] != "" {
+				n, _ := strconv.Atoi(m[2])
+				nth = ordinalWords[n]
+			}
+			days = append(days, "on the "+nth+" "+weekdayName(m[1])+" of the month")
+		} else {
+			days = append(days, "on "+describeList(dow, "day", weekdayName))
+		}
+	}
+	return strings.Join(days, " or ")
+}
+
+// describeList reads a list field such as "1-5", "MON,WED,FRI" or "0-30/10",
+// naming values with name when it is set.
+func describeList(field, unit string, name func(string) string) string {
+	if name == nil {
+		name = func(v string) string { return v }
+	}
+
+	var items []string
+	for _, item := range strings.Split(field, ",") {
+		step := ""
+		if i := strings.Index(item, "/"); i >= 0 {
+			item, step = item[:i], item[i+1:]
+		}
+		var desc string
+		if lo, hi, ok := strings.Cut(item, "-"); ok {
+			desc = name(lo) + " through " + name(hi)
+		} else if item == "*" {
+			desc = "every " + unit
+		} else {
+			desc = name(item)
+		}
+		if step != "" {
+			desc = "every " + step + " " + unit + "s from " + desc
+		}
+		items = append(items, desc)
+	}
+
+	if len(items) == 1 {
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+func weekdayName(v string) string {
+	if n, err := strconv.Atoi(v); err == nil && n >= 0 && n <= 7 {
+		return time.Weekday(n % 7).String()
+	}
+	for i, name := range cronWeekdays {
+		if strings.EqualFold(name, v) {
+			return time.Weekday(i).String()
+		}
+	}
+	return v
+}
+
+func monthName(v string) string {
+	if n, err := strconv.Atoi(v); err == nil && n >= 1 && n <= 12 {
+		return time.Month(n).String()
+	}
+	for i, name := range cronMonths {
+		if i > 0 && strings.EqualFold(name, v) {
+			return time.Month(i).String()
+		}
+	}
+	return v
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// nextFireTimes returns up to n upcoming times of schedule after from.
+func nextFireTimes(schedule cron.Schedule, from time.Time, n int) []time.Time {
+	var times []time.Time
+	for len(times) < n {
+		from = schedule.Next(from)
+		if from.IsZero() {
+			break
+		}
+		times = append(times, from)
+	}
+	return times
+}
+
+// formatFireTimes renders times as Discord timestamps separated by sep.
+func formatFireTimes(times []time.Time, sep string) string {
+	stamps := make([]string, len(times))
+	for i, t := range times {
+		stamps[i] = fmt.Sprintf("<t:%d:F>", t.Unix())
+	}
+	return strings.Join(stamps, sep)
+}
+
+func handlePreviewCommand(s *discordgo.Session, m *discordgo.MessageCreate, parts []string) {
+	ctx := newMessageContext(s, m)
+
+	if len(parts) < 2 {
+		ctx.reply("Usage: !preview `seconds minutes hours day_of_month month day_of_week` or !preview every <schedule>")
+		return
+	}
+
+	expr := strings.Join(parts[1:], " ")
+	if args := parseBacktickArgs(expr); strings.HasPrefix(expr, "`") && len(args) > 0 {
+		expr = args[0]
+	}
+
+	runPreview(ctx, expr)
+}
+
+// runPreview explains a cron expression or schedule phrase and lists its
+// next fire times without creating a reminder.
+func runPreview(ctx *commandContext, expr string) {
+	if isRecurrencePhrase(expr) {
+		compiled, err := compileRecurrence(expr)
+		if err != nil {
+			ctx.reply("Could not understand the schedule: " + err.Error())
+			return
+		}
+		expr = compiled
+	}
+
+	loc := userLocation(ctx.userID)
+	schedule, err := parseCron(expr, loc)
+	if err != nil {
+		ctx.reply("Invalid cron expression: " + err.Error())
+		return
+	}
+
+	times := nextFireTimes(schedule, time.Now(), previewCount)
+	if len(times) == 0 {
+		ctx.reply(fmt.Sprintf("`%s`: %s\nThis schedule never fires.", expr, describeCron(expr)))
+		return
+	}
+	ctx.reply(fmt.Sprintf("`%s`: %s\nNext %d times (%s):\n%s", expr, describeCron(expr), len(times), loc, formatFireTimes(times, "\n")))
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"container/heap"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// schedulerPollInterval is how often the scheduler reloads upcoming
+	// reminders from the store.
+	schedulerPollInterval = time.Minute
+	// schedulerLookahead is how far ahead reminders are held in memory. It
+	// must exceed schedulerPollInterval so nothing slips between reloads.
+	schedulerLookahead = 10 * time.Minute
+)
+
+// heapScheduler fires one-shot reminders from a single goroutine. Only
+// reminders due within the lookahead window are kept in memory, in a min-heap
+// ordered by delivery time; later ones stay in the store until a reload brings
+// them into the window, so reminders months out cost no timers or memory.
+type heapScheduler struct {
+	mu         sync.Mutex
+	queue      reminderQueue
+	items      map[int]*queueItem
+	horizon    time.Time
+	nextReload time.Time
+	// firing holds the IDs being fired. Their rows stay due in the store
+	// until delivery finishes, so reloads must not queue them again.
+	firing map[int]bool
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+
+	load func(before time.Time) ([]Reminder, error)
+	fire func(r Reminder)
+}
+
+func newHeapScheduler(load func(before time.Time) ([]Reminder, error), fire func(r Reminder)) *heapScheduler {
+	return &heapScheduler{
+		items:  make(map[int]*queueItem),
+		firing: make(map[int]bool),
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		load:   load,
+		fire:   fire,
+	}
+}
+
+func (hs *heapScheduler) Start() {
+	go hs.run()
+}
+
+func (hs *heapScheduler) Stop() {
+	close(hs.stop)
+	<-hs.done
+}
+
+// Add schedules r, replacing any pending entry with the same ID. Reminders
+// beyond the current window are left to the next reload.
+func (hs *heapScheduler) Add(r Reminder) {
+	hs.mu.Lock()
+	hs.removeLocked(r.ID)
+	if r.deliveryTime().Before(hs.horizon) {
+		item := &queueItem{r: r}
+		heap.Push(&hs.queue, item)
+		hs.items[r.ID] = item
+	}
+	hs.mu.Unlock()
+	hs.notify()
+}
+
+func (hs *heapScheduler) Remove(id int) {
+	hs.mu.Lock()
+	hs.removeLocked(id)
+	hs.mu.Unlock()
+}
+
+func (hs *heapScheduler) removeLocked(id int) {
+	if item, ok := hs.items[id]; ok {
+		heap.Remove(&hs.queue, item.index)
+		delete(hs.items, id)
+	}
+}
+
+func (hs *heapScheduler) notify() {
+	select {
+	case hs.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (hs *heapScheduler) run() {
+	defer close(hs.done)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-hs.stop:
+			return
+		case <-hs.wake:
+		case <-timer.C:
+		}
+
+		now := time.Now()
+
+		hs.mu.Lock()
+		if !now.Before(hs.nextReload) {
+			hs.reloadLocked(now)
+		}
+
+		var due []Reminder
+		for hs.queue.Len() > 0 && !hs.queue[0].r.deliveryTime().After(now) {
+			item := heap.Pop(&hs.queue).(*queueItem)
+			delete(hs.items, item.r.ID)
+			hs.firing[item.r.ID] = true
+			due = append(due, item.r)
+		}
+
+		next := hs.nextReload
+		if hs.queue.Len() > 0 && hs.queue[0].r.deliveryTime().Before(next) {
+			next = hs.queue[0].r.deliveryTime()
+		}
+		hs.mu.Unlock()
+
+		for _, r := range due {
+			go func(r Reminder) {
+				hs.fire(r)
+				hs.mu.Lock()
+				delete(hs.firing, r.ID)
+				hs.mu.Unlock()
+			}(r)
+		}
+
+		timer.Reset(time.Until(next))
+	}
+}
+
+// reloadLocked replaces the in-memory window with what the store holds. The
+// lock is kept across the query so a concurrent Add cannot be lost. Reminders
+// being fired keep only what fire itself re-added, e.g. the next nag.
+func (hs *heapScheduler) reloadLocked(now time.Time) {
+	hs.nextReload = now.Add(schedulerPollInterval)
+
+	horizon := now.Add(schedulerLookahead)
+	list, err := hs.load(horizon)
+	if err != nil {
+		log.Printf("Error loading due reminders: %v", err)
+		return
+	}
+
+	old := hs.items
+	hs.queue = hs.queue[:0]
+	hs.items = make(map[int]*queueItem, len(list))
+	push := func(r Reminder) {
+		item := &queueItem{r: r, index: len(hs.queue)}
+		hs.queue = append(hs.queue, item)
+		hs.items[r.ID] = item
+	}
+	for _, r := range list {
+		if !hs.firing[r.ID] {
+			push(r)
+		}
+	}
+	for id := range hs.firing {
+		if item, ok := old[id]; ok {
+			push(item.r)
+		}
+	}
+	heap.Init(&hs.queue)
+	hs.horizon = horizon
+}
+
+type queueItem struct {
+	r     Reminder
+	index int
+}
+
+// reminderQueue is a container/heap min-heap of reminders by delivery time.
+type reminderQueue []*queueItem
+
+func (q reminderQueue) Len() int { return len(q) }
+
+func (q reminderQueue) Less(i, j int) bool {
+	return q[i].r.deliveryTime().Before(q[j].r.deliveryTime())
+}
+
+func (q reminderQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *reminderQueue) Push(x any) {
+	item := x.(*queueItem)
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+func (q *reminderQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return item
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// userLocation returns the timezone chosen by the user, or the default
+// timezone when none is set or it can no longer be loaded.
+func userLocation(userID string) *time.Location {
+	tz, err := store.GetUserTimezone(userID)
+	if err != nil {
+		log.Printf("Error fetching timezone for user %s: %v", userID, err)
+		return time.Local
+	}
+	if tz == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		log.Printf("Error loading timezone %q for user %s: %v", tz, userID, err)
+		return time.Local
+	}
+	return loc
+}
+
+// reminderLocation returns the timezone a recurring reminder's cron fields
+// are evaluated in: its own when set, otherwise its owner's.
+func reminderLocation(r Reminder) *time.Location {
+	if r.Timezone == "" {
+		return userLocation(r.UserID)
+	}
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		log.Printf("Error loading timezone %q for reminder %d: %v", r.Timezone, r.ID, err)
+		return userLocation(r.UserID)
+	}
+	return loc
+}
+
+func handleTimezoneCommand(s *discordgo.Session, m *discordgo.MessageCreate, parts []string) {
+	ctx := newMessageContext(s, m)
+
+	if len(parts) > 2 {
+		ctx.reply("Usage: !timezone [IANA name, e.g. Europe/Berlin]")
+		return
+	}
+
+	name := ""
+	if len(parts) == 2 {
+		name = parts[1]
+	}
+	runTimezone(ctx, name)
+}
+
+// runTimezone shows the user's timezone, or sets it when name is given and
+// moves their recurring reminders without a timezone of their own onto the
+// new zone.
+func runTimezone(ctx *commandContext, name string) {
+	if name == "" {
+		ctx.reply(fmt.Sprintf("Your timezone is %s", userLocation(ctx.userID)))
+		return
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		ctx.reply("Unknown timezone. Use an IANA name such as Europe/Berlin or America/New_York.")
+		return
+	}
+
+	err = store.SetUserTimezone(ctx.userID, loc.String())
+	if err != nil {
+		ctx.reply("Error setting timezone: " + err.Error())
+		return
+	}
+
+	list, err := store.ListUserReminders(ctx.userID)
+	if err != nil {
+		log.Printf("Error fetching recurring reminders: %v", err)
+	}
+	for _, r := range list {
+		if r.CronExpr.Valid && r.CronExpr.String != "" && r.Timezone == "" {
+			rescheduleRecurringReminder(ctx.s, r.ID, r)
+		}
+	}
+
+	ctx.reply(fmt.Sprintf("Timezone set to %s", loc))
+}
+
+// quietHours is a daily window, in minutes after midnight, during which a
+// user's reminders are held back. A window that wraps past midnight has
+// start after end.
+type quietHours struct {
+	start, end int
+}
+
+func (q quietHours) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", q.start/60, q.start%60, q.end/60, q.end%60)
+}
+
+// parseQuietHours reads a window such as "22:00-07:00" or "10pm-7am".
+func parseQuietHours(s string) (quietHours, error) {
+	from, to, ok := strings.Cut(strings.ToLower(strings.ReplaceAll(s, " ", "")), "-")
+	if !ok {
+		return quietHours{}, fmt.Errorf("expected a window such as 22:00-07:00")
+	}
+	start, err := parseClockMinutes(from)
+	if err != nil {
+		return quietHours{}, err
+	}
+	end, err := parseClockMinutes(to)
+	if err != nil {
+		return quietHours{}, err
+	}
+	if start == end {
+		return quietHours{}, fmt.Errorf("quiet hours must not start and end at the same time")
+	}
+	return quietHours{start: start, end: end}, nil
+}
+
+func parseClockMinutes(s string) (int, error) {
+	p := &naturalTime{}
+	if p.clock([]string{s}, true) != 1 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return p.hour*60 + p.minute, nil
+}
+
+// quietUntil returns when the user's quiet hours that t falls in end, and
+// false if t is outside them or the user has none.
+func quietUntil(userID string, t time.Time) (time.Time, bool) {
+	spec, err := store.GetUserQuietHours(userID)
+	if err != nil {
+		log.Printf("Error fetching quiet hours for user %s: %v", userID, err)
+		return time.Time{}, false
+	}
+	if spec == "" {
+		return time.Time{}, false
+	}
+	q, err := parseQuietHours(spec)
+	if err != nil {
+		log.Printf("Error parsing quiet hours %q for user %s: %v", spec, userID, err)
+		return time.Time{}, false
+	}
+
+	local := t.In(userLocation(userID))
+	minute := local.Hour()*60 + local.Minute()
+	inside := minute >= q.start && minute < q.end
+	if q.start > q.end {
+		inside = minute >= q.start || minute < q.end
+	}
+	if !inside {
+		return time.Time{}, false
+	}
+
+	end := time.Date(local.Year(), local.Month(), local.Day(), q.end/60, q.end%60, 0, 0, local.Location())
+	if !end.After(local) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end, true
+}
+
+func handleQuietCommand(s *discordgo.Session, m *discordgo.MessageCreate, parts []string) {
+	ctx := newMessageContext(s, m)
+
+	if len(parts) > 2 {
+		ctx.reply("Usage: !quiet [22:00-07:00|off]")
+		return
+	}
+
+	hours := ""
+	if len(parts) == 2 {
+		hours = parts[1]
+	}
+	runQuiet(ctx, hours)
+}
+
+// runQuiet shows the user's quiet hours, or sets them when hours is given.
+// "off" clears them.
+func runQuiet(ctx *commandContext, hours string) {
+	if hours == "" {
+		current, err := store.GetUserQuietHours(ctx.userID)
+		if err != nil {
+			ctx.reply("Error fetching quiet hours: " + err.Error())
+			return
+		}
+		if current == "" {
+			ctx.reply("You have no quiet hours set")
+		} else {
+			ctx.reply(fmt.Sprintf("Your quiet hours are %s (%s)", current, userLocation(ctx.userID)))
+		}
+		return
+	}
+
+	if strings.EqualFold(hours, "off") {
+		if err := store.SetUserQuietHours(ctx.userID, ""); err != nil {
+			ctx.reply("Error clearing quiet hours: " + err.Error())
+			return
+		}
+		ctx.reply("Quiet hours cleared")
+		return
+	}
+
+	q, err := parseQuietHours(hours)
+	if err != nil {
+		ctx.reply("Invalid quiet hours: " + err.Error())
+		return
+	}
+	if err := store.SetUserQuietHours(ctx.userID, q.String()); err != nil {
+		ctx.reply("Error setting quiet hours: " + err.Error())
+		return
+	}
+	ctx.reply(fmt.Sprintf("Quiet hours set to %s (%s). Reminders due then are held until they end; mark a reminder urgent to bypass this.", q, userLocation(ctx.userID)))
+}
+
+// userPrefersDM reports whether the user asked for all their reminders to be
+// delivered by DM.
+func userPrefersDM(userID string) bool {
+	dm, err := store.GetUserDMDefault(userID)
+	if err != nil {
+		log.Printf("Error fetching DM preference for user %s: %v", userID, err)
+		return false
+	}
+	return dm
+}
+
+func handleDMCommand(s *discordgo.Session, m *discordgo.MessageCreate, parts []string) {
+	ctx := newMessageContext(s, m)
+
+	if len(parts) > 2 {
+		ctx.reply("Usage: !dm [on|off]")
+		return
+	}
+
+	value := ""
+	if len(parts) == 2 {
+		value = parts[1]
+	}
+	runDM(ctx, value)
+}
+
+// runDM shows whether the user's reminders go to their DMs by default, or
+// changes it when value is "on" or "off".
+func runDM(ctx *commandContext, value string) {
+	switch strings.ToLower(value) {
+	case "":
+		if userPrefersDM(ctx.userID) {
+			ctx.reply("Your reminders are delivered by DM")
+		} else {
+			ctx.reply("Your reminders are delivered in the channel they were set in. Use --dm on a reminder or !dm on to change that.")
+		}
+	case "on", "off":
+		dm := strings.EqualFold(value, "on")
+		if err := store.SetUserDMDefault(ctx.userID, dm); err != nil {
+			ctx.reply("Error saving DM preference: " + err.Error())
+			return
+		}
+		if dm {
+			ctx.reply("Your reminders will now be delivered by DM, except those that ping other people")
+		} else {
+			ctx.reply("Your reminders will now be delivered in the channel they were set in, unless set with --dm")
+		}
+	default:
+		ctx.reply("Usage: !dm [on|off]")
+	}
+}
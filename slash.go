@@ -0,0 +1,428 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var slashCommands = []*discordgo.ApplicationCommand{
+	{
+		Name:        "remind",
+		Description: "Set a one-time reminder",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "when",
+				Description: "Duration (5m, 2h), time (15:04, 3pm) or phrase (tomorrow at 9am)",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "message",
+				Description: "What to remind you about",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "nag",
+				Description: "Re-ping at this interval (e.g. 10m) until you press Done",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "mentions",
+				Description: "Users and roles to ping instead of you, e.g. @alice @oncall",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "urgent",
+				Description: "Deliver even during your quiet hours",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "warn",
+				Description: "Send heads-ups this long before, e.g. 1h,10m",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "dm",
+				Description: "Deliver to your DMs instead of this channel",
+			},
+		},
+	},
+	{
+		Name:        "recurring",
+		Description: "Set a recurring reminder from a cron expression or phrase",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "cron",
+				Description: "Cron (sec min hour dom month dow) or phrase (every weekday at 9:30)",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "message",
+				Description: "What to remind you about",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "until",
+				Description: "Stop after this time, e.g. 2025-12-31 or in 2 weeks",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "times",
+				Description: "Stop after this many occurrences",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "tz",
+				Description: "IANA timezone for this reminder, e.g. Europe/Berlin",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "skip-holidays",
+				Description: "Holiday calendar whose dates are skipped, e.g. indonesia",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "urgent",
+				Description: "Deliver even during your quiet hours",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "warn",
+				Description: "Send heads-ups this long before, e.g. 1h,10m",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "dm",
+				Description: "Deliver to your DMs instead of this channel",
+			},
+		},
+	},
+	{
+		Name:        "list",
+		Description: "List your reminders",
+	},
+	{
+		Name:        "delete",
+		Description: "Delete one of your reminders",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "id",
+				Description: "Reminder ID",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "pause",
+		Description: "Pause a recurring reminder, optionally for a while",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "id",
+				Description: "Reminder ID",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "for",
+				Description: "Resume after this long, e.g. 2w",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "until",
+				Description: "Resume at this time, e.g. next monday 9am",
+			},
+		},
+	},
+	{
+		Name:        "resume",
+		Description: "Resume a paused recurring reminder",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "id",
+				Description: "Reminder ID",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "skip",
+		Description: "Skip upcoming occurrences of a recurring reminder",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "id",
+				Description: "Reminder ID",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "count",
+				Description: "How many more occurrences to skip (default 1, 0 to stop skipping)",
+			},
+		},
+	},
+	{
+		Name:        "edit",
+		Description: "Change the time, cron expression, timezone or message of a reminder",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "id",
+				Description: "Reminder ID",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "field",
+				Description: "What to change",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "time", Value: "time"},
+					{Name: "cron", Value: "cron"},
+					{Name: "timezone", Value: "timezone"},
+					{Name: "message", Value: "message"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "value",
+				Description: "New value",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "export",
+		Description: "Receive your active reminders as JSON via DM",
+	},
+	{
+		Name:        "import",
+		Description: "Recreate reminders from a file produced by /export",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionAttachment,
+				Name:        "file",
+				Description: "reminders.json",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "preview",
+		Description: "Explain a cron expression or phrase and show its next fire times",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "cron",
+				Description: "Cron (sec min hour dom month dow) or phrase (every weekday at 9:30)",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "quiet",
+		Description: "Show or set the hours during which your reminders are held back",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "hours",
+				Description: "Window such as 22:00-07:00, or off",
+			},
+		},
+	},
+	{
+		Name:        "dm",
+		Description: "Show or set whether your reminders are delivered by DM by default",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "enabled",
+				Description: "Deliver all your reminders by DM",
+			},
+		},
+	},
+	{
+		Name:        "timezone",
+		Description: "Show or set your timezone",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "name",
+				Description: "IANA timezone name, e.g. Europe/Berlin",
+			},
+		},
+	},
+	{
+		Name: remindMessageCommand,
+		Type: discordgo.MessageApplicationCommand,
+	},
+}
+
+// registerSlashCommands overwrites the bot's application commands. Commands
+// are registered globally unless guildID is set, which makes them show up
+// immediately in that guild and is handy during development.
+func registerSlashCommands(s *discordgo.Session, guildID string) error {
+	_, err := s.ApplicationCommandBulkOverwrite(s.State.User.ID, guildID, slashCommands)
+	return err
+}
+
+func newInteractionContext(s *discordgo.Session, i *discordgo.InteractionCreate) *commandContext {
+	return interactionContext(s, i, false)
+}
+
+// newDeferredInteractionContext acknowledges i straight away for commands
+// that may take longer than Discord's three seconds to answer. The first
+// reply then replaces the "thinking" placeholder.
+func newDeferredInteractionContext(s *discordgo.Session, i *discordgo.InteractionCreate) *commandContext {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+	return interactionContext(s, i, true)
+}
+
+func interactionContext(s *discordgo.Session, i *discordgo.InteractionCreate, deferred bool) *commandContext {
+	responded := false
+	return &commandContext{
+		s:         s,
+		guildID:   i.GuildID,
+		channelID: i.ChannelID,
+		userID:    interactionUserID(i),
+		reply: func(content string) {
+			if responded {
+				s.FollowupMessageCreate(i.Interaction, false, &discordgo.WebhookParams{
+					Content: content,
+					Flags:   discordgo.MessageFlagsEphemeral,
+				})
+				return
+			}
+			responded = true
+			if deferred {
+				s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
+				return
+			}
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: content,
+					Flags:   discordgo.MessageFlagsEphemeral,
+				},
+			})
+		},
+	}
+}
+
+// interactionUserID returns the invoking user, which is set on Member inside
+// guilds and on User in DMs.
+func interactionUserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
+// optionValues collects the given string options that were supplied, in the
+// same shape extractOptions produces for ! commands.
+func optionValues(options map[string]*discordgo.ApplicationCommandInteractionDataOption, names ...string) map[string]string {
+	values := make(map[string]string)
+	for _, name := range names {
+		opt, ok := options[name]
+		if !ok {
+			continue
+		}
+		switch opt.Type {
+		case discordgo.ApplicationCommandOptionInteger:
+			values[name] = strconv.FormatInt(opt.IntValue(), 10)
+		case discordgo.ApplicationCommandOptionBoolean:
+			values[name] = strconv.FormatBool(opt.BoolValue())
+		default:
+			values[name] = opt.StringValue()
+		}
+	}
+	return values
+}
+
+func handleApplicationCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	ctx := newInteractionContext(s, i)
+
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(data.Options))
+	for _, opt := range data.Options {
+		options[opt.Name] = opt
+	}
+
+	switch data.Name {
+	case remindMessageCommand:
+		handleRemindMessageCommand(s, i)
+	case "remind":
+		runRemind(ctx, options["when"].StringValue(), options["message"].StringValue(), optionValues(options, "nag", "mentions", "urgent", "warn", "dm"))
+	case "recurring":
+		runRecurring(ctx, options["cron"].StringValue(), options["message"].StringValue(), optionValues(options, "until", "times", "tz", "skip-holidays", "urgent", "warn", "dm"))
+	case "list":
+		listReminders(ctx)
+	case "delete":
+		runDelete(ctx, int(options["id"].IntValue()))
+	case "pause":
+		runPause(ctx, int(options["id"].IntValue()), optionValues(options, "for", "until"))
+	case "resume":
+		runResume(ctx, int(options["id"].IntValue()))
+	case "skip":
+		n := 1
+		if opt, ok := options["count"]; ok {
+			n = int(opt.IntValue())
+		}
+		if n < 0 {
+			ctx.reply("Invalid count. Use a number of occurrences, or 0 to stop skipping.")
+			return
+		}
+		runSkip(ctx, int(options["id"].IntValue()), n)
+	case "edit":
+		runEdit(ctx, int(options["id"].IntValue()), options["field"].StringValue(), options["value"].StringValue())
+	case "export":
+		handleExportCommand(ctx)
+	case "import":
+		attachmentID, _ := options["file"].Value.(string)
+		if data.Resolved == nil || data.Resolved.Attachments[attachmentID] == nil {
+			ctx.reply("Attachment not found")
+			return
+		}
+		// Downloading and saving every reminder can outlast the interaction
+		// deadline, so acknowledge first.
+		runImport(newDeferredInteractionContext(s, i), data.Resolved.Attachments[attachmentID].URL)
+	case "timezone":
+		name := ""
+		if opt, ok := options["name"]; ok {
+			name = opt.StringValue()
+		}
+		runTimezone(ctx, name)
+	case "preview":
+		runPreview(ctx, options["cron"].StringValue())
+	case "quiet":
+		hours := ""
+		if opt, ok := options["hours"]; ok {
+			hours = opt.StringValue()
+		}
+		runQuiet(ctx, hours)
+	case "dm":
+		value := ""
+		if opt, ok := options["enabled"]; ok {
+			value = "off"
+			if opt.BoolValue() {
+				value = "on"
+			}
+		}
+		runDM(ctx, value)
+	}
+}
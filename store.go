@@ -0,0 +1,424 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Store persists reminders and per-user settings.
+type Store interface {
+	SaveReminder(r Reminder) (int, error)
+	GetReminder(id int) (Reminder, error)
+	GetReminderUserID(id int) (string, error)
+	// UpdateReminder writes the message, due time, cron expression, delivery
+	// time, nag state, occurrence limits, timezone, holiday calendar and
+	// quiet hours queue and hold of r.
+	UpdateReminder(r Reminder) error
+	// SetReminderPaused pauses or resumes a recurring reminder. A non-zero
+	// until records when the pause ends on its own.
+	SetReminderPaused(id int, paused bool, until time.Time) error
+	// SetReminderSkips sets how many upcoming occurrences of a recurring
+	// reminder are suppressed.
+	SetReminderSkips(id int, skips int) error
+	// AddReminderSkips adds n, which may be negative, to the pending skips
+	// of a recurring reminder and returns the new count.
+	AddReminderSkips(id int, n int) (int, error)
+	DeleteReminder(id int) error
+	ListReminders() ([]Reminder, error)
+	// ListUserReminders returns the user's reminders that have not been
+	// delivered yet.
+	ListUserReminders(userID string) ([]Reminder, error)
+	// ListDueReminders returns one-shot reminders to be delivered before the
+	// given time, earliest first. Reminders held for quiet hours are ordered
+	// by the end of the hold.
+	ListDueReminders(before time.Time) ([]Reminder, error)
+	// PurgeDeliveredReminders deletes reminders delivered before the given
+	// time.
+	PurgeDeliveredReminders(before time.Time) error
+	// ListEndedReminders returns recurring reminders whose until time is
+	// before the given time.
+	ListEndedReminders(before time.Time) ([]Reminder, error)
+	// RecordNag moves a nagging reminder to its next ping, records how many
+	// have been sent and ends any quiet hours hold, leaving the other columns
+	// alone.
+	RecordNag(id int, due time.Time, count int) error
+	// MarkReminderDelivered records when a one-shot reminder was delivered
+	// and ends any quiet hours hold, leaving the other columns alone.
+	MarkReminderDelivered(id int, at time.Time) error
+	// DeferReminder holds a one-shot reminder for quiet hours until the
+	// given time without changing its due time.
+	DeferReminder(id int, until time.Time) error
+	// QueueReminderOccurrence holds back one occurrence of a recurring
+	// reminder for quiet hours.
+	QueueReminderOccurrence(id int) error
+	// RecordReminderFires counts n occurrences of a recurring reminder and
+	// clears its quiet hours queue, leaving the other columns alone.
+	RecordReminderFires(id int, n int) error
+	// ListExpiredPauses returns paused reminders whose pause ends before the
+	// given time.
+	ListExpiredPauses(before time.Time) ([]Reminder, error)
+	// ListQueuedReminders returns recurring reminders with occurrences held
+	// back by quiet hours.
+	ListQueuedReminders() ([]Reminder, error)
+	// ListWarnedReminders returns the pending, unpaused reminders that have
+	// advance warnings.
+	ListWarnedReminders() ([]Reminder, error)
+	// SetReminderWarnedAt records when the last advance warning sent for a
+	// reminder was due.
+	SetReminderWarnedAt(id int, at time.Time) error
+
+	GetUserTimezone(userID string) (string, error)
+	SetUserTimezone(userID, tz string) error
+	// GetUserQuietHours returns the user's quiet hours as "22:00-07:00", or
+	// "" when none are set.
+	GetUserQuietHours(userID string) (string, error)
+	SetUserQuietHours(userID, hours string) error
+	// GetUserDMDefault reports whether the user wants all their reminders
+	// delivered by DM.
+	GetUserDMDefault(userID string) (bool, error)
+	SetUserDMDefault(userID string, dm bool) error
+
+	Close() error
+}
+
+// newStore opens the backend selected by driver ("sqlite" or "postgres").
+// dsn is a file path for SQLite and a connection string for Postgres.
+func newStore(driver, dsn string) (Store, error) {
+	switch driver {
+	case "", "sqlite", "sqlite3":
+		return newSQLiteStore(dsn)
+	case "postgres", "postgresql":
+		return newPostgresStore(dsn)
+	default:
+		return nil, fmt.Errorf("unknown database driver: %s", driver)
+	}
+}
+
+// sqlStore implements Store on top of database/sql. Queries are written with
+// ? placeholders and rebound for drivers that use another style.
+type sqlStore struct {
+	db      *sql.DB
+	dialect string
+}
+
+func (st *sqlStore) rebind(query string) string {
+	if st.dialect == dialectPostgres {
+		return rebindDollar(query)
+	}
+	return query
+}
+
+const reminderColumns = "id, channel_id, user_id, message, due_time, cron_expr, paused, delivered_at, nag_interval, nag_count, mentions, ends_at, max_fires, fire_count, timezone, skip_count, paused_until, holiday_calendar, urgent, queued_count, warnings, warned_at, dm, source_guild_id, source_channel_id, source_message_id, quote, deferred_until"
+
+// formatDueTime renders due times in UTC so they sort and compare correctly
+// as text regardless of the zone the user entered them in.
+func formatDueTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatWarningLeads renders lead times for the warnings column.
+func formatWarningLeads(leads []time.Duration) string {
+	parts := make([]string, len(leads))
+	for i, d := range leads {
+		parts[i] = d.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// nullTime stores the zero time as NULL and anything else like formatDueTime.
+func nullTime(t time.Time) sql.NullString {
+	if t.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{Valid: true, String: formatDueTime(t)}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanReminder(row rowScanner) (Reminder, error) {
+	var r Reminder
+	var dueTimeStr, deliveredAtStr, mentions, endsAtStr, timezone, pausedUntilStr, holidayCalendar, warnings, warnedAtStr sql.NullString
+	var sourceGuildID, sourceChannelID, sourceMessageID, quote, deferredUntilStr sql.NullString
+	var nagSeconds int64
+	err := row.Scan(&r.ID, &r.ChannelID, &r.UserID, &r.Message, &dueTimeStr, &r.CronExpr, &r.Paused, &deliveredAtStr, &nagSeconds, &r.NagCount, &mentions,
+		&endsAtStr, &r.MaxFires, &r.FireCount, &timezone, &r.SkipCount, &pausedUntilStr, &holidayCalendar,
+		&r.Urgent, &r.QueuedCount, &warnings, &warnedAtStr, &r.DM,
+		&sourceGuildID, &sourceChannelID, &sourceMessageID, &quote, &deferredUntilStr)
+	if err != nil {
+		return Reminder{}, err
+	}
+	r.NagInterval = time.Duration(nagSeconds) * time.Second
+	r.Mentions = strings.Fields(mentions.String)
+	r.Timezone = timezone.String
+	r.HolidayCalendar = holidayCalendar.String
+	r.SourceGuildID = sourceGuildID.String
+	r.SourceChannelID = sourceChannelID.String
+	r.SourceMessageID = sourceMessageID.String
+	r.Quote = quote.String
+	if dueTimeStr.Valid && dueTimeStr.String != "" {
+		// A malformed due time leaves DueTime zero rather than hiding the
+		// rest of the user's reminders.
+		r.DueTime, err = time.Parse(time.RFC3339, dueTimeStr.String)
+		if err != nil {
+			log.Printf("Error parsing due time of reminder %d: %v", r.ID, err)
+		}
+	}
+	if deliveredAtStr.Valid && deliveredAtStr.String != "" {
+		r.DeliveredAt, err = time.Parse(time.RFC3339, deliveredAtStr.String)
+		if err != nil {
+			log.Printf("Error parsing delivery time of reminder %d: %v", r.ID, err)
+		}
+	}
+	if deferredUntilStr.Valid && deferredUntilStr.String != "" {
+		r.DeferredUntil, err = time.Parse(time.RFC3339, deferredUntilStr.String)
+		if err != nil {
+			log.Printf("Error parsing quiet hours hold of reminder %d: %v", r.ID, err)
+		}
+	}
+	if endsAtStr.Valid && endsAtStr.String != "" {
+		r.Until, err = time.Parse(time.RFC3339, endsAtStr.String)
+		if err != nil {
+			log.Printf("Error parsing end time of reminder %d: %v", r.ID, err)
+		}
+	}
+	for _, v := range strings.Split(warnings.String, ",") {
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("Error parsing warning of reminder %d: %v", r.ID, err)
+			continue
+		}
+		r.Warnings = append(r.Warnings, d)
+	}
+	if warnedAtStr.Valid && warnedAtStr.String != "" {
+		r.WarnedAt, err = time.Parse(time.RFC3339, warnedAtStr.String)
+		if err != nil {
+			log.Printf("Error parsing warning time of reminder %d: %v", r.ID, err)
+		}
+	}
+	if pausedUntilStr.Valid && pausedUntilStr.String != "" {
+		r.PausedUntil, err = time.Parse(time.RFC3339, pausedUntilStr.String)
+		if err != nil {
+			log.Printf("Error parsing pause end of reminder %d: %v", r.ID, err)
+		}
+	}
+	return r, nil
+}
+
+func (st *sqlStore) exec(query string, args ...any) (sql.Result, error) {
+	return st.db.Exec(st.rebind(query), args...)
+}
+
+func (st *sqlStore) queryRow(query string, args ...any) *sql.Row {
+	return st.db.QueryRow(st.rebind(query), args...)
+}
+
+func (st *sqlStore) queryReminders(query string, args ...any) ([]Reminder, error) {
+	rows, err := st.db.Query(st.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Reminder
+	for rows.Next() {
+		r, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+func (st *sqlStore) SaveReminder(r Reminder) (int, error) {
+	var id int
+	var err error
+
+	if r.CronExpr.Valid && r.CronExpr.String != "" {
+		err = st.queryRow("INSERT INTO reminders (channel_id, user_id, message, cron_expr, mentions, ends_at, max_fires, fire_count, timezone, skip_count, holiday_calendar, urgent, warnings, dm) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id",
+			r.ChannelID, r.UserID, r.Message, r.CronExpr, strings.Join(r.Mentions, " "), nullTime(r.Until), r.MaxFires, r.FireCount, r.Timezone, r.SkipCount,
+			r.HolidayCalendar, r.Urgent, formatWarningLeads(r.Warnings), r.DM).Scan(&id)
+	} else {
+		err = st.queryRow("INSERT INTO reminders (channel_id, user_id, message, due_time, nag_interval, mentions, urgent, warnings, dm, source_guild_id, source_channel_id, source_message_id, quote) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id",
+			r.ChannelID, r.UserID, r.Message, formatDueTime(r.DueTime), int64(r.NagInterval/time.Second), strings.Join(r.Mentions, " "), r.Urgent,
+			formatWarningLeads(r.Warnings), r.DM, r.SourceGuildID, r.SourceChannelID, r.SourceMessageID, r.Quote).Scan(&id)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (st *sqlStore) GetReminder(id int) (Reminder, error) {
+	return scanReminder(st.queryRow("SELECT "+reminderColumns+" FROM reminders WHERE id = ?", id))
+}
+
+func (st *sqlStore) GetReminderUserID(id int) (string, error) {
+	var userID string
+	err := st.queryRow("SELECT user_id FROM reminders WHERE id = ?", id).Scan(&userID)
+	if err != nil {
+		return "", err
+	}
+	return userID, nil
+}
+
+func (st *sqlStore) UpdateReminder(r Reminder) error {
+	_, err := st.exec("UPDATE reminders SET message = ?, due_time = ?, cron_expr = ?, delivered_at = ?, nag_interval = ?, nag_count = ?, ends_at = ?, max_fires = ?, fire_count = ?, timezone = ?, holiday_calendar = ?, queued_count = ?, deferred_until = ? WHERE id = ?",
+		r.Message, nullTime(r.DueTime), r.CronExpr, nullTime(r.DeliveredAt), int64(r.NagInterval/time.Second), r.NagCount,
+		nullTime(r.Until), r.MaxFires, r.FireCount, r.Timezone, r.HolidayCalendar, r.QueuedCount, nullTime(r.DeferredUntil), r.ID)
+	return err
+}
+
+func (st *sqlStore) SetReminderPaused(id int, paused bool, until time.Time) error {
+	_, err := st.exec("UPDATE reminders SET paused = ?, paused_until = ? WHERE id = ?", paused, nullTime(until), id)
+	return err
+}
+
+func (st *sqlStore) SetReminderSkips(id int, skips int) error {
+	_, err := st.exec("UPDATE reminders SET skip_count = ? WHERE id = ?", skips, id)
+	return err
+}
+
+func (st *sqlStore) AddReminderSkips(id int, n int) (int, error) {
+	var skips int
+	err := st.queryRow("UPDATE reminders SET skip_count = CASE WHEN skip_count + ? > 0 THEN skip_count + ? ELSE 0 END WHERE id = ? RETURNING skip_count",
+		n, n, id).Scan(&skips)
+	return skips, err
+}
+
+func (st *sqlStore) DeleteReminder(id int) error {
+	_, err := st.exec("DELETE FROM reminders WHERE id = ?", id)
+	return err
+}
+
+func (st *sqlStore) ListReminders() ([]Reminder, error) {
+	return st.queryReminders("SELECT " + reminderColumns + " FROM reminders ORDER BY id")
+}
+
+func (st *sqlStore) ListUserReminders(userID string) ([]Reminder, error) {
+	return st.queryReminders("SELECT "+reminderColumns+" FROM reminders WHERE user_id = ? AND delivered_at IS NULL ORDER BY id", userID)
+}
+
+func (st *sqlStore) ListDueReminders(before time.Time) ([]Reminder, error) {
+	return st.queryReminders("SELECT "+reminderColumns+" FROM reminders WHERE (cron_expr IS NULL OR cron_expr = '') AND delivered_at IS NULL AND COALESCE(deferred_until, due_time) <= ? ORDER BY COALESCE(deferred_until, due_time)",
+		formatDueTime(before))
+}
+
+func (st *sqlStore) PurgeDeliveredReminders(before time.Time) error {
+	_, err := st.exec("DELETE FROM reminders WHERE delivered_at IS NOT NULL AND delivered_at <= ?", formatDueTime(before))
+	return err
+}
+
+func (st *sqlStore) ListEndedReminders(before time.Time) ([]Reminder, error) {
+	return st.queryReminders("SELECT "+reminderColumns+" FROM reminders WHERE ends_at IS NOT NULL AND ends_at <= ? ORDER BY id", formatDueTime(before))
+}
+
+func (st *sqlStore) RecordNag(id int, due time.Time, count int) error {
+	_, err := st.exec("UPDATE reminders SET due_time = ?, nag_count = ?, deferred_until = NULL WHERE id = ?", formatDueTime(due), count, id)
+	return err
+}
+
+func (st *sqlStore) MarkReminderDelivered(id int, at time.Time) error {
+	_, err := st.exec("UPDATE reminders SET delivered_at = ?, deferred_until = NULL WHERE id = ?", nullTime(at), id)
+	return err
+}
+
+func (st *sqlStore) DeferReminder(id int, until time.Time) error {
+	_, err := st.exec("UPDATE reminders SET deferred_until = ? WHERE id = ?", nullTime(until), id)
+	return err
+}
+
+func (st *sqlStore) QueueReminderOccurrence(id int) error {
+	_, err := st.exec("UPDATE reminders SET queued_count = queued_count + 1 WHERE id = ?", id)
+	return err
+}
+
+func (st *sqlStore) RecordReminderFires(id int, n int) error {
+	_, err := st.exec("UPDATE reminders SET fire_count = fire_count + ?, queued_count = 0 WHERE id = ?", n, id)
+	return err
+}
+
+func (st *sqlStore) ListExpiredPauses(before time.Time) ([]Reminder, error) {
+	return st.queryReminders("SELECT "+reminderColumns+" FROM reminders WHERE paused = ? AND paused_until IS NOT NULL AND paused_until <= ? ORDER BY id",
+		true, formatDueTime(before))
+}
+
+func (st *sqlStore) ListQueuedReminders() ([]Reminder, error) {
+	return st.queryReminders("SELECT " + reminderColumns + " FROM reminders WHERE cron_expr IS NOT NULL AND cron_expr <> '' AND queued_count > 0 ORDER BY id")
+}
+
+func (st *sqlStore) ListWarnedReminders() ([]Reminder, error) {
+	return st.queryReminders("SELECT "+reminderColumns+" FROM reminders WHERE warnings IS NOT NULL AND warnings <> '' AND delivered_at IS NULL AND paused = ? ORDER BY id", false)
+}
+
+func (st *sqlStore) SetReminderWarnedAt(id int, at time.Time) error {
+	_, err := st.exec("UPDATE reminders SET warned_at = ? WHERE id = ?", nullTime(at), id)
+	return err
+}
+
+func (st *sqlStore) GetUserTimezone(userID string) (string, error) {
+	var tz sql.NullString
+	err := st.queryRow("SELECT timezone FROM user_settings WHERE user_id = ?", userID).Scan(&tz)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return tz.String, nil
+}
+
+func (st *sqlStore) SetUserTimezone(userID, tz string) error {
+	_, err := st.exec(`INSERT INTO user_settings (user_id, timezone) VALUES (?, ?)
+        ON CONFLICT(user_id) DO UPDATE SET timezone = excluded.timezone`, userID, tz)
+	return err
+}
+
+func (st *sqlStore) GetUserQuietHours(userID string) (string, error) {
+	var hours sql.NullString
+	err := st.queryRow("SELECT quiet_hours FROM user_settings WHERE user_id = ?", userID).Scan(&hours)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return hours.String, nil
+}
+
+func (st *sqlStore) SetUserQuietHours(userID, hours string) error {
+	_, err := st.exec(`INSERT INTO user_settings (user_id, quiet_hours) VALUES (?, ?)
+        ON CONFLICT(user_id) DO UPDATE SET quiet_hours = excluded.quiet_hours`, userID, hours)
+	return err
+}
+
+func (st *sqlStore) GetUserDMDefault(userID string) (bool, error) {
+	var dm bool
+	err := st.queryRow("SELECT dm_default FROM user_settings WHERE user_id = ?", userID).Scan(&dm)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return dm, nil
+}
+
+func (st *sqlStore) SetUserDMDefault(userID string, dm bool) error {
+	_, err := st.exec(`INSERT INTO user_settings (user_id, dm_default) VALUES (?, ?)
+        ON CONFLICT(user_id) DO UPDATE SET dm_default = excluded.dm_default`, userID, dm)
+	return err
+}
+
+func (st *sqlStore) Close() error {
+	return st.db.Close()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+func newPostgresStore(dsn string) (*sqlStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	st := &sqlStore{db: db, dialect: dialectPostgres}
+	if err := st.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return st, nil
+}
+
+// rebindDollar rewrites ? placeholders into Postgres' $1, $2, ... style.
+func rebindDollar(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
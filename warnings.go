@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// maxWarnings caps how many --warn lead times a reminder may have.
+	maxWarnings = 5
+	// warningLateness is how late a heads-up may still be sent. Older ones,
+	// e.g. for a reminder created 5 minutes before it is due with --warn 1h,
+	// are dropped.
+	warningLateness = time.Minute
+)
+
+// parseWarnings reads a --warn list such as "1h,10m", returning the lead
+// times longest first.
+func parseWarnings(s string) ([]time.Duration, error) {
+	var leads []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := parseDuration(part)
+		if err != nil || d < time.Minute {
+			return nil, fmt.Errorf("invalid --warn lead time %q, use e.g. 1h,10m", part)
+		}
+		leads = append(leads, d)
+	}
+	if len(leads) == 0 {
+		return nil, fmt.Errorf("--warn needs at least one lead time, e.g. 1h,10m")
+	}
+	if len(leads) > maxWarnings {
+		return nil, fmt.Errorf("--warn accepts at most %d lead times", maxWarnings)
+	}
+	sort.Slice(leads, func(i, j int) bool { return leads[i] > leads[j] })
+	return leads, nil
+}
+
+// humanDuration renders d as "1 hour 30 minutes", dropping seconds.
+func humanDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	var parts []string
+	for _, p := range []struct {
+		n    int
+		unit string
+	}{{days, "day"}, {hours, "hour"}, {minutes, "minute"}} {
+		switch {
+		case p.n == 1:
+			parts = append(parts, "1 "+p.unit)
+		case p.n > 1:
+			parts = append(parts, fmt.Sprintf("%d %ss", p.n, p.unit))
+		}
+	}
+	if len(parts) == 0 {
+		return "less than a minute"
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatWarnings(leads []time.Duration) string {
+	names := make([]string, len(leads))
+	for i, d := range leads {
+		names[i] = humanDuration(d)
+	}
+	return strings.Join(names, ", ")
+}
+
+// nextOccurrence returns when r is next delivered, or false if that is not
+// known or warnings do not apply to its current state.
+func nextOccurrence(r Reminder, now time.Time) (time.Time, bool) {
+	if !r.CronExpr.Valid || r.CronExpr.String == "" {
+		// Nag re-pings and deliveries held by quiet hours are not warned
+		// about again.
+		if r.DueTime.IsZero() || !r.DeliveredAt.IsZero() || r.NagCount > 0 || !r.DeferredUntil.IsZero() {
+			return time.Time{}, false
+		}
+		return r.DueTime, true
+	}
+
+	if r.Paused {
+		return time.Time{}, false
+	}
+	schedule, err := reminderSchedule(r)
+	if err != nil {
+		return time.Time{}, false
+	}
+	times := nextFireTimes(schedule, now, r.SkipCount+1)
+	if len(times) <= r.SkipCount {
+		return time.Time{}, false
+	}
+	return times[r.SkipCount], true
+}
+
+// sendDueWarnings posts the heads-up notices whose lead time has been
+// reached. Progress is kept in WarnedAt, so each notice is sent once even
+// across restarts, and deleting a reminder cancels its pending notices.
+func sendDueWarnings(s *discordgo.Session) {
+	list, err := store.ListWarnedReminders()
+	if err != nil {
+		log.Printf("Error fetching reminders with warnings: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, r := range list {
+		// One-shots outside the longest lead time are passed over before
+		// anything is computed for them.
+		if len(r.Warnings) == 0 || (!r.DueTime.IsZero() && r.DueTime.Sub(now) > r.Warnings[0]) {
+			continue
+		}
+		occurrence, ok := nextOccurrence(r, now)
+		if !ok || !occurrence.After(now) || occurrence.Sub(now) > r.Warnings[0] {
+			continue
+		}
+
+		// When several lead times have passed, e.g. after a restart, only
+		// the most recent one is sent.
+		var due time.Time
+		for _, lead := range r.Warnings {
+			at := occurrence.Add(-lead)
+			if at.After(now) || !at.After(r.WarnedAt) {
+				continue
+			}
+			if at.After(due) {
+				due = at
+			}
+		}
+		if due.IsZero() {
+			continue
+		}
+
+		if err := store.SetReminderWarnedAt(r.ID, due); err != nil {
+			log.Printf("Error recording warning for reminder %d: %v", r.ID, err)
+			continue
+		}
+		if now.Sub(due) > warningLateness {
+			continue
+		}
+		if !r.Urgent {
+			if _, quiet := quietUntil(r.UserID, now); quiet {
+				continue
+			}
+		}
+
+		sendDelivery(s, r, &discordgo.MessageSend{
+			Content: fmt.Sprintf("%s Heads-up: '%s' in %s (<t:%d:F>)",
+				r.recipients(), r.Message, humanDuration(occurrence.Sub(now)), occurrence.Unix()),
+		})
+	}
+}